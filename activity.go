@@ -0,0 +1,93 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Had jejak aktiviti dalam memori
+const (
+	activityMaxUsers   = 10000
+	activityMaxHistory = 10
+)
+
+// guideView ialah satu paparan panduan/infografik oleh user
+type guideView struct {
+	Guide string    `json:"guide"`
+	At    time.Time `json:"at"`
+}
+
+// userActivityInfo ialah aktiviti terakhir seorang user sejak bot dimulakan (untuk /whois)
+type userActivityInfo struct {
+	Username   string      `json:"username,omitempty"`
+	LastSeen   time.Time   `json:"last_seen"`
+	LastAction string      `json:"last_action"`
+	Guides     []guideView `json:"guides"` // terbaru di hujung, maksimum activityMaxHistory
+}
+
+var (
+	activityLog = make(map[int64]*userActivityInfo)
+	activityMu  sync.Mutex
+)
+
+// activityEntry memulangkan entri user (dipanggil dengan activityMu dikunci)
+func activityEntry(userID int64) *userActivityInfo {
+	info, ok := activityLog[userID]
+	if ok {
+		return info
+	}
+	if len(activityLog) >= activityMaxUsers {
+		// Buang user yang paling lama tidak aktif
+		var oldestID int64
+		var oldest time.Time
+		for id, a := range activityLog {
+			if oldest.IsZero() || a.LastSeen.Before(oldest) {
+				oldestID, oldest = id, a.LastSeen
+			}
+		}
+		delete(activityLog, oldestID)
+	}
+	info = &userActivityInfo{}
+	activityLog[userID] = info
+	return info
+}
+
+// recordActivity merekod tindakan terakhir user
+func recordActivity(userID int64, username string, action string) {
+	if len([]rune(action)) > 64 {
+		action = string([]rune(action)[:64]) + "…"
+	}
+	activityMu.Lock()
+	defer activityMu.Unlock()
+	info := activityEntry(userID)
+	if username != "" {
+		info.Username = username
+	}
+	info.LastSeen = time.Now()
+	info.LastAction = action
+}
+
+// countGuide mengira paparan panduan dalam statistik dan sejarah user
+func countGuide(userID int64, stat string) {
+	countStat(stat)
+	activityMu.Lock()
+	defer activityMu.Unlock()
+	info := activityEntry(userID)
+	info.Guides = append(info.Guides, guideView{Guide: stat, At: time.Now()})
+	if len(info.Guides) > activityMaxHistory {
+		info.Guides = info.Guides[len(info.Guides)-activityMaxHistory:]
+	}
+}
+
+// activityOf memulangkan salinan aktiviti user (false jika tiada sejak bot dimulakan)
+func activityOf(userID int64) (userActivityInfo, bool) {
+	activityMu.Lock()
+	defer activityMu.Unlock()
+	info, ok := activityLog[userID]
+	if !ok {
+		return userActivityInfo{}, false
+	}
+	cp := *info
+	cp.Guides = append([]guideView(nil), info.Guides...)
+	return cp, true
+}
@@ -1,81 +1,109 @@
 package main
 
 import (
+	"errors"
+	"expvar"
 	"fmt"
+	"log"
+	"strings"
 	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// ===== DATA ADMIN =====
-const (
-	ADMIN_USER_ID  int64  = 7348614053
-	ADMIN_USERNAME string = "JohanSetia"
-	ADMIN_NAME     string = "Mr JOHAN (MACGYVER CODERMAN)"
-)
-
-// Struktur untuk menjejak aktiviti setiap user
-type UserActivity struct {
-	LastAction time.Time
-	Count      int
+// Metrik pembersih anti-spam (dipaparkan bersama statistik pengehad dalam expvar "antispam")
+var spamJanitor struct {
+	sync.Mutex
+	runs          int64
+	lastRun       time.Time
+	lastDuration  time.Duration
+	strikesPruned int64
 }
 
-var (
-	spamMap = make(map[int64]*UserActivity)
-	spamMu  sync.Mutex
-	// Had limit: 5 tekanan dalam masa 3 saat
-	threshold      = 5
-	timeWindow     = 3 * time.Second
-)
-
-// IsAdminID menyemak sama ada user ID adalah Admin
-func IsAdminID(userID int64) bool {
-	return userID == ADMIN_USER_ID
+func init() {
+	expvar.Publish("antispam", expvar.Func(func() interface{} {
+		spamJanitor.Lock()
+		defer spamJanitor.Unlock()
+		spamStrikes.Lock()
+		strikes, cooldowns := len(spamStrikes.records), len(spamStrikes.cooldown)
+		spamStrikes.Unlock()
+		limiters := make(map[string]RateLimiterStats)
+		for role, lim := range allSpamLimiters() {
+			limiters[strings.ToLower(role.String())] = lim.Stats()
+		}
+		return map[string]interface{}{
+			"limiters":         limiters,
+			"strikes_tracked":  strikes,
+			"cooldowns_active": cooldowns,
+			"janitor_runs":     spamJanitor.runs,
+			"janitor_last_run": spamJanitor.lastRun,
+			"janitor_last_ms":  spamJanitor.lastDuration.Milliseconds(),
+			"strikes_pruned":   spamJanitor.strikesPruned,
+		}
+	}))
 }
 
-// CheckSpam akan memulangkan 'true' jika user disahkan spammer
-// PENTING: Fungsi ini TIDAK akan mengesan spam untuk Admin
-func CheckSpam(userID int64) bool {
-	// Admin dikecualikan dari sistem anti-spam
-	if IsAdminID(userID) {
-		return false // Admin tidak akan dianggap spammer
+// startSpamJanitor membuang keadaan anti-spam yang tidak lagi diperlukan secara berkala:
+// bucket yang telah penuh semula dan sejarah kesalahan di luar tempoh lookback. Nilai
+// terakhir polisi last_known yang melepasi LAST_KNOWN_TTL turut dibuang.
+func startSpamJanitor(interval time.Duration) {
+	if interval <= 0 {
+		return
 	}
-	
-	spamMu.Lock()
-	defer spamMu.Unlock()
-
-	now := time.Now()
-	activity, exists := spamMap[userID]
+	go func() {
+		for {
+			time.Sleep(interval)
+			sweepSpamState(time.Now())
+		}
+	}()
+}
 
-	if !exists {
-		spamMap[userID] = &UserActivity{LastAction: now, Count: 1}
-		return false
+func sweepSpamState(now time.Time) {
+	start := time.Now()
+	buckets := 0
+	for _, lim := range allSpamLimiters() {
+		buckets += lim.Sweep()
 	}
+	strikes := pruneSpamStrikes(now, strikeRetention())
+	known := sweepLastKnown(now)
 
-	// Jika masih dalam tingkap masa (3 saat)
-	if now.Sub(activity.LastAction) < timeWindow {
-		activity.Count++
-	} else {
-		// Reset jika sudah lama tidak tekan
-		activity.Count = 1
+	spamJanitor.Lock()
+	spamJanitor.runs++
+	spamJanitor.lastRun = now
+	spamJanitor.lastDuration = time.Since(start)
+	spamJanitor.strikesPruned += int64(strikes)
+	spamJanitor.Unlock()
+	if buckets > 0 || strikes > 0 || known > 0 {
+		log.Printf("🧹 Anti-spam: %d bucket dibuang, sejarah kesalahan %d user dipangkas, %d nilai terakhir lapuk dibuang",
+			buckets, strikes, known)
 	}
+}
 
-	activity.LastAction = now
+// CheckSpam akan memulangkan 'true' jika user disahkan spammer (token habis)
+// PENTING: Pasukan moderasi dikecualikan secara lalai (boleh diaktifkan dengan
+// /antispam set <peranan>.enabled on, tetapi tangga hukuman tidak dikenakan)
+func CheckSpam(userID int64, kind ActionKind) bool {
+	limiter, enabled := spamLimiterFor(RoleOf(userID))
+	if !enabled {
+		return false
+	}
 
 	// Jika melebihi had, aktifkan hukuman
-	if activity.Count > threshold {
+	if !limiter.Allow(userID, kind) {
+		countStat(statSpamDetected)
 		return true
 	}
 
 	return false
 }
 
-// ExecuteAutoBan menjalankan hukuman dan menghantar notis denda
-// PENTING: Fungsi ini TIDAK akan menjalankan ban untuk Admin
-func ExecuteAutoBan(bot *tgbotapi.BotAPI, chatID int64, userID int64, username string) {
+// ExecuteAutoBan menjalankan hukuman dan menghantar notis denda.
+// duration > 0 menjadikan sekatan sementara (langkah tangga hukuman anti-spam).
+// PENTING: Fungsi ini TIDAK akan menjalankan ban untuk pasukan moderasi
+func ExecuteAutoBan(bot *tgbotapi.BotAPI, chatID int64, userID int64, username string, duration time.Duration) {
 	// Langkah keselamatan: Jangan ban Admin
-	if IsAdminID(userID) {
+	if HasPermission(userID, PermBypassChecks) {
 		logMsg := fmt.Sprintf("⚠️ PERHATIAN: Percubaan ban Admin dikesan! User: @%s (ID: %d) - TINDAKAN DIBATALKAN", username, userID)
 		fmt.Println(logMsg)
 		
@@ -89,67 +117,153 @@ func ExecuteAutoBan(bot *tgbotapi.BotAPI, chatID int64, userID int64, username s
 			"📋 Nama: %s\n"+
 			"⏰ Masa: %s\n\n"+
 			"_Sistem melindungi Admin daripada sekatan automatik._", 
-			username, userID, ADMIN_NAME, time.Now().Format("2006-01-02 15:04:05"))
+			username, userID, StaffName(userID, username), time.Now().Format("2006-01-02 15:04:05"))
 		
-		msg := tgbotapi.NewMessage(ADMIN_USER_ID, adminAlert)
-		msg.ParseMode = tgbotapi.ModeMarkdown
-		bot.Send(msg)
+		notifyStaff(bot, PermSystemAlerts, adminAlert)
 		return
 	}
 	
-	// 1. Simpan rekod sekatan ke GitHub (Audit Log)
+	// 1. Simpan rekod sekatan ke storan (Audit Log) - dijurnal dahulu, dicuba semula di latar belakang
 	reason := "AUTO-BAN: Melakukan kesalahan spamming butang/mesej"
-	BanUser(userID, reason)
+	detail := "kekal"
+	if duration > 0 {
+		detail = "tamat " + time.Now().Add(duration).Format("2006-01-02 15:04")
+	}
+	if err := BanUser(userID, reason, autoBanActor, duration); err != nil {
+		log.Printf("❌ Gagal jurnal auto-ban user %d: %v", userID, err)
+	} else {
+		countStat(statBanAuto)
+		logAudit(AuditRecord{Actor: autoBanActor, Action: auditBan, Target: userID, Reason: reason, Source: auditSourceAuto, Detail: detail})
+	}
 
 	// 2. Bina mesej notis sekatan dan denda
 	// Gunakan Markdown Standard yang serasi dengan main.go
 	notisSaman := fmt.Sprintf(
 		"🚫 **AKAUN ANDA TELAH DISEKAT**\n\n"+
-			"Sistem mengesan aktiviti spam yang melampau dari akaun anda.\n\n"+
-			"**Tindakan:** Sekatan Kekal (Permanent Ban)\n\n"+
-			"Untuk membuka semula sekatan ini, anda wajib:\n"+
-			"1. Mengemukakan rayuan kepada Admin.\n"+
-			"2. Menjelaskan denda kesalahan (Bayaran) jika ingin unlock.\n\n"+
-			"👉 **Hubungi Admin untuk Rayuan:** [KLIK DI SINI](https://t.me/johansetia)\n\n"+
-			"_Sila sertakan ID anda (%d) semasa membuat rayuan._", userID)
+			"Sistem mengesan aktiviti spam berulang dari akaun anda.\n\n"+
+			"**Tindakan:** Sekatan Sementara sehingga %s\n\n"+
+			"Sekatan akan ditarik balik secara automatik selepas tempoh ini. "+
+			"Jika berulang lagi, hukuman akan dinaikkan sehingga sekatan kekal.\n\n"+
+			"👉 **Rayuan:** taip `/rayuan <penjelasan anda>` terus di dalam bot ini (sekali sahaja).\n\n"+
+			"_ID Rujukan: %d_", time.Now().Add(duration).Format("2006-01-02 15:04"), userID)
+	if duration <= 0 {
+		notisSaman = fmt.Sprintf(
+			"🚫 **AKAUN ANDA TELAH DISEKAT**\n\n"+
+				"Sistem mengesan aktiviti spam yang melampau dari akaun anda.\n\n"+
+				"**Tindakan:** Sekatan Kekal (Permanent Ban)\n\n"+
+				"Untuk membuka semula sekatan ini, anda wajib:\n"+
+				"1. Mengemukakan rayuan kepada Admin.\n"+
+				"2. Menjelaskan denda kesalahan (Bayaran) jika ingin unlock.\n\n"+
+				"👉 **Rayuan:** taip `/rayuan <penjelasan anda>` terus di dalam bot ini (sekali sahaja).\n\n"+
+				"_ID Rujukan: %d_", userID)
+	}
 
 	msg := tgbotapi.NewMessage(chatID, notisSaman)
 	msg.ParseMode = tgbotapi.ModeMarkdown
 	msg.DisableWebPagePreview = false
 	bot.Send(msg)
 
-	// 3. Laporkan kepada Admin (Mr JOHAN) supaya tahu ada 'pelanggan' baru nak bayar denda
+	status := "Menunggu Saman"
+	if duration > 0 {
+		status = "Sekatan sementara, " + detail
+	}
+	strikes := 0
+	if rec, ok := StrikeHistory(userID); ok {
+		strikes = len(rec.Events)
+	}
+
+	// 3. Laporkan kepada moderator supaya tahu ada 'pelanggan' baru nak bayar denda
 	adminLog := fmt.Sprintf(
 		"📢 **RADAR ALERT: AUTO-BAN**\n\n"+
 		"👤 User: @%s\n"+
 		"🆔 ID: `%d`\n"+
-		"📋 Status: Menunggu Saman\n"+
+		"📋 Status: %s\n"+
+		"⚠️ Kesalahan spam direkod: %d (semak `/whois %d`)\n"+
 		"⏰ Masa: %s", 
-		username, userID, time.Now().Format("2006-01-02 15:04:05"))
+		username, userID, status, strikes, userID, time.Now().Format("2006-01-02 15:04:05"))
 	
-	report := tgbotapi.NewMessage(ADMIN_USER_ID, adminLog)
-	report.ParseMode = tgbotapi.ModeMarkdown
-	bot.Send(report)
+	notifyStaff(bot, PermBan, adminLog)
 }
 
-// UnbanUser - Fungsi untuk membuang sekatan (untuk kegunaan Admin)
-func UnbanUser(bot *tgbotapi.BotAPI, adminID int64, targetID int64, chatID int64) error {
-	// Pastikan hanya Admin boleh unban
-	if !IsAdminID(adminID) {
-		return fmt.Errorf("hanya Admin boleh menggunakan fungsi unban")
+// autoBanActor ialah nilai by_admin bagi sekatan automatik anti-spam
+const autoBanActor = "Sistem (Auto-Ban)"
+
+// errNotBanned dipulangkan oleh UnbanUser jika user tiada rekod sekatan
+var errNotBanned = errors.New("user tidak disekat")
+
+// ResetSpam membuang kiraan spam seorang user
+func ResetSpam(userID int64) {
+	for _, lim := range allSpamLimiters() {
+		lim.Reset(userID)
 	}
-	
-	// Logik untuk unban dari GitHub akan ditambah di sini
-	// (perlu diintegrasikan dengan fungsi dari terms.go)
-	
+}
+
+// UnbanUser - Fungsi untuk membuang sekatan (untuk kegunaan Admin).
+// Memulangkan notified=false jika notis tidak dapat dihantar kepada user.
+func UnbanUser(bot *tgbotapi.BotAPI, adminID int64, targetID int64) (notified bool, err error) {
+	// Pastikan hanya pentadbir dengan kebenaran boleh unban
+	if !HasPermission(adminID, PermUnban) {
+		return false, fmt.Errorf("tiada kebenaran untuk menggunakan fungsi unban")
+	}
+
+	rec, err := store.GetBan(targetID)
+	if err != nil {
+		return false, fmt.Errorf("gagal semak rekod sekatan: %v", err)
+	}
+	if rec == nil || !rec.Active(time.Now()) {
+		return false, errNotBanned
+	}
+
+	// Padam blacklist/<id>.json (atau rekod setara dalam backend lain); sejarah
+	// tindakan ini kekal dalam log audit
+	if err := store.DeleteBan(targetID); err != nil {
+		return false, fmt.Errorf("gagal padam rekod sekatan: %v", err)
+	}
+	clearBanState(targetID)
+	clearSpamStrikes(targetID)
+	log.Printf("🔓 User %d dinyahsekat oleh %s", targetID, StaffName(adminID, ""))
+	logAudit(AuditRecord{Actor: StaffName(adminID, ""), ActorID: adminID, Action: auditUnban, Target: targetID,
+		Detail: fmt.Sprintf("sekatan asal: %s (oleh %s)", rec.Reason, rec.ByAdmin)})
+
 	notisUnban := fmt.Sprintf(
 		"✅ **NOTIS PENARIKAN SEKATAN**\n\n"+
 		"Akaun anda (ID: `%d`) telah **DINYAHSEKAT** oleh Admin.\n\n"+
 		"Anda kini boleh menggunakan bot semula. Sila taip /start untuk mula.", targetID)
 	
-	msg := tgbotapi.NewMessage(targetID, notisUnban)
-	msg.ParseMode = tgbotapi.ModeMarkdown
-	bot.Send(msg)
-	
+	return sendLiftNotice(bot, targetID, notisUnban), nil
+}
+
+// liftBan menanda rekod sekatan sebagai ditarik balik (tamat tempoh) dan membersihkan semua
+// keadaan sekatan user dalam memori. Rekod (termasuk rayuan & keputusan Admin) disimpan untuk sejarah.
+func liftBan(rec BanRecord, now time.Time) error {
+	rec.LiftedAt = &now
+	if err := store.SaveBan(rec); err != nil {
+		return fmt.Errorf("gagal kemaskini rekod sekatan: %v", err)
+	}
+	clearBanState(rec.UserID)
 	return nil
 }
+
+// clearBanState membuang status sekatan user dari cache, nilai terakhir dan kiraan spam
+func clearBanState(targetID int64) {
+	// Buang status sekatan dari cache supaya user tidak terus disekat oleh cache
+	if storeCache != nil {
+		storeCache.Invalidate(targetID)
+	}
+	lastKnown.Lock()
+	lastKnown.banned.delete(targetID)
+	lastKnown.Unlock()
+	ResetSpam(targetID)
+}
+
+// sendLiftNotice menghantar notis penarikan sekatan; false jika user tidak dapat dihubungi
+func sendLiftNotice(bot *tgbotapi.BotAPI, targetID int64, text string) bool {
+	msg := tgbotapi.NewMessage(targetID, text)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	if _, err := bot.Send(msg); err != nil {
+		// User mungkin sudah block bot atau tidak aktif
+		log.Printf("Gagal hantar notis penarikan sekatan ke user %d: %v", targetID, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kunci tetapan anti-spam: "[peranan.]kunci", contoh "burst", "user.media", "support.enabled".
+// Selain kunci di bawah, nama jenis tindakan (callback, text, media, command) menetapkan kos.
+const (
+	spamKeyEnabled = "enabled"
+	spamKeyBurst   = "burst"
+	spamKeyRefill  = "refill"
+)
+
+// allRoles ialah semua peranan (susunan paparan)
+var allRoles = []Role{RoleUser, RoleSupport, RoleModerator, RoleOwner}
+
+// roleSpamLimit ialah had anti-spam berkesan bagi satu peranan
+type roleSpamLimit struct {
+	Enabled bool
+	RateLimit
+}
+
+// spamSettingKey ialah kunci tetapan yang telah dihurai
+type spamSettingKey struct {
+	Role  *Role // nil = semua peranan
+	Field string
+}
+
+func (k spamSettingKey) String() string {
+	if k.Role == nil {
+		return k.Field
+	}
+	return strings.ToLower(k.Role.String()) + "." + k.Field
+}
+
+// parseSpamSettingKey menghurai "[peranan.]kunci"
+func parseSpamSettingKey(s string) (spamSettingKey, error) {
+	var key spamSettingKey
+	s = strings.ToLower(strings.TrimSpace(s))
+	if prefix, field, ok := strings.Cut(s, "."); ok {
+		role, known := parseRole(prefix)
+		if !known && prefix != "user" {
+			return key, fmt.Errorf("peranan tidak dikenali: %q (user, support, moderator, owner)", prefix)
+		}
+		key.Role, s = &role, field
+	}
+	switch s {
+	case spamKeyEnabled, spamKeyBurst, spamKeyRefill:
+	default:
+		if _, ok := defaultActionCosts[ActionKind(s)]; !ok {
+			return key, fmt.Errorf("kunci tidak dikenali: %q (enabled, burst, refill, callback, text, media, command)", s)
+		}
+	}
+	key.Field = s
+	return key, nil
+}
+
+// apply menetapkan nilai kunci ke atas had satu peranan
+func (k spamSettingKey) apply(limit *roleSpamLimit, value string) error {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch k.Field {
+	case spamKeyEnabled:
+		switch value {
+		case "on", "true", "1":
+			limit.Enabled = true
+		case "off", "false", "0":
+			limit.Enabled = false
+		default:
+			return fmt.Errorf("nilai %s mesti on/off: %q", k.Field, value)
+		}
+	case spamKeyBurst:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("nilai %s mesti nombor positif: %q", k.Field, value)
+		}
+		limit.Burst = n
+	case spamKeyRefill:
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("nilai %s mesti tempoh positif (contoh 500ms, 2s): %q", k.Field, value)
+		}
+		limit.Refill = d
+	default:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("kos %s mesti nombor >= 0: %q", k.Field, value)
+		}
+		limit.Costs[ActionKind(k.Field)] = n
+	}
+	return nil
+}
+
+// parseSpamOverrides membaca senarai "[peranan.]kunci=nilai" dipisahkan koma
+func parseSpamOverrides(s string) map[string]string {
+	overrides := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(entry, "=")
+		if !ok {
+			log.Printf("⚠️ Entri SPAM_OVERRIDES tidak sah (%q), diabaikan", entry)
+			continue
+		}
+		overrides[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	return overrides
+}
+
+// buildSpamLimits mengira had berkesan setiap peranan: had asas dari konfigurasi
+// (pasukan moderasi dikecualikan secara lalai), kemudian setiap lapisan override.
+// Kunci tanpa peranan digunakan dahulu supaya kunci khusus peranan sentiasa menang.
+func buildSpamLimits(cfg Config, layers ...map[string]string) (map[Role]roleSpamLimit, error) {
+	limits := make(map[Role]*roleSpamLimit, len(allRoles))
+	for _, role := range allRoles {
+		costs := make(map[ActionKind]float64, len(cfg.SpamCosts))
+		for k, v := range cfg.SpamCosts {
+			costs[k] = v
+		}
+		limits[role] = &roleSpamLimit{
+			Enabled:   !role.Can(PermBypassChecks),
+			RateLimit: RateLimit{Burst: float64(cfg.SpamBurst), Refill: cfg.SpamRefill, Costs: costs},
+		}
+	}
+
+	for _, roleSpecific := range []bool{false, true} {
+		for _, layer := range layers {
+			names := make([]string, 0, len(layer))
+			for name := range layer {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				key, err := parseSpamSettingKey(name)
+				if err != nil {
+					return nil, err
+				}
+				if (key.Role != nil) != roleSpecific {
+					continue
+				}
+				targets := allRoles
+				if key.Role != nil {
+					targets = []Role{*key.Role}
+				}
+				for _, role := range targets {
+					if err := key.apply(limits[role], layer[name]); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	result := make(map[Role]roleSpamLimit, len(limits))
+	for role, l := range limits {
+		result[role] = *l
+	}
+	return result, nil
+}
+
+// spamSettings ialah keadaan semasa anti-spam: override masa jalan (/antispam set,
+// disimpan dalam STATE_DIR/antispam.json) dan pengehad kadar bagi setiap peranan
+var spamSettings = struct {
+	sync.Mutex
+	runtime  map[string]string
+	limits   map[Role]roleSpamLimit
+	limiters map[Role]*RateLimiter
+}{runtime: make(map[string]string), limiters: make(map[Role]*RateLimiter)}
+
+func spamSettingsPath() string {
+	return filepath.Join(config.StateDir, "antispam.json")
+}
+
+// initAntiSpam membina pengehad kadar dari konfigurasi dan override yang disimpan
+func initAntiSpam(cfg Config) {
+	runtime := make(map[string]string)
+	if data, err := os.ReadFile(spamSettingsPath()); err == nil {
+		if err := json.Unmarshal(data, &runtime); err != nil {
+			log.Printf("⚠️ Gagal baca %s: %v", spamSettingsPath(), err)
+			runtime = make(map[string]string)
+		}
+	}
+	limits, err := buildSpamLimits(cfg, cfg.SpamOverrides, runtime)
+	if err != nil {
+		log.Printf("⚠️ Tetapan anti-spam tidak sah (%v), override disimpan diabaikan", err)
+		runtime = make(map[string]string)
+		if limits, err = buildSpamLimits(cfg, cfg.SpamOverrides); err != nil {
+			log.Printf("⚠️ SPAM_OVERRIDES tidak sah (%v), diabaikan", err)
+			limits, _ = buildSpamLimits(cfg)
+		}
+	}
+
+	spamSettings.Lock()
+	defer spamSettings.Unlock()
+	spamSettings.runtime = runtime
+	spamSettings.limits = limits
+	spamSettings.limiters = make(map[Role]*RateLimiter, len(limits))
+	for role, l := range limits {
+		spamSettings.limiters[role] = NewRateLimiter(l.RateLimit, cfg.SpamMaxTracked, nil)
+	}
+	if len(runtime) > 0 {
+		log.Printf("🛡️ Anti-spam: %d override dari /antispam dimuatkan", len(runtime))
+	}
+}
+
+// spamLimiterFor memulangkan pengehad kadar peranan dan sama ada ia diaktifkan
+func spamLimiterFor(role Role) (*RateLimiter, bool) {
+	spamSettings.Lock()
+	defer spamSettings.Unlock()
+	lim, ok := spamSettings.limiters[role]
+	if !ok {
+		// initAntiSpam belum dipanggil: guna had asas
+		lim = NewRateLimiter(RateLimit{Burst: 6, Refill: time.Second, Costs: defaultActionCosts}, 0, nil)
+		spamSettings.limiters[role] = lim
+		return lim, !role.Can(PermBypassChecks)
+	}
+	return lim, spamSettings.limits[role].Enabled
+}
+
+// allSpamLimiters memulangkan salinan senarai pengehad kadar setiap peranan
+func allSpamLimiters() map[Role]*RateLimiter {
+	spamSettings.Lock()
+	defer spamSettings.Unlock()
+	cp := make(map[Role]*RateLimiter, len(spamSettings.limiters))
+	for role, lim := range spamSettings.limiters {
+		cp[role] = lim
+	}
+	return cp
+}
+
+// SetSpamSetting menukar (value != "") atau membuang (value == "") override masa jalan,
+// menggunakannya serta-merta dan menyimpannya. Memulangkan nilai override sebelumnya.
+func SetSpamSetting(name, value string) (key spamSettingKey, previous string, err error) {
+	key, err = parseSpamSettingKey(name)
+	if err != nil {
+		return key, "", err
+	}
+
+	spamSettings.Lock()
+	defer spamSettings.Unlock()
+	runtime := make(map[string]string, len(spamSettings.runtime)+1)
+	for k, v := range spamSettings.runtime {
+		runtime[k] = v
+	}
+	previous = runtime[key.String()]
+	if value == "" {
+		delete(runtime, key.String())
+	} else {
+		runtime[key.String()] = value
+	}
+	limits, err := buildSpamLimits(config, config.SpamOverrides, runtime)
+	if err != nil {
+		return key, previous, err
+	}
+
+	data, _ := json.MarshalIndent(runtime, "", "  ")
+	if err := os.MkdirAll(config.StateDir, 0o755); err != nil {
+		return key, previous, fmt.Errorf("gagal simpan tetapan anti-spam: %v", err)
+	}
+	if err := os.WriteFile(spamSettingsPath(), data, 0o644); err != nil {
+		return key, previous, fmt.Errorf("gagal simpan tetapan anti-spam: %v", err)
+	}
+
+	spamSettings.runtime = runtime
+	spamSettings.limits = limits
+	for role, l := range limits {
+		if lim, ok := spamSettings.limiters[role]; ok {
+			lim.SetLimit(l.RateLimit)
+		} else {
+			spamSettings.limiters[role] = NewRateLimiter(l.RateLimit, config.SpamMaxTracked, nil)
+		}
+	}
+	return key, previous, nil
+}
+
+// spamLimitsSnapshot meringkaskan had berkesan setiap peranan (konfigurasi, SPAM_OVERRIDES
+// dan override /antispam) dalam satu baris, contoh "user=6/1s/callback:1,text:1,... support=off"
+func spamLimitsSnapshot() string {
+	spamSettings.Lock()
+	defer spamSettings.Unlock()
+	parts := make([]string, 0, len(allRoles))
+	for _, role := range allRoles {
+		l, ok := spamSettings.limits[role]
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(role.String())
+		if !l.Enabled {
+			parts = append(parts, name+"=off")
+			continue
+		}
+		costs := make([]string, len(actionKinds))
+		for i, kind := range actionKinds {
+			costs[i] = fmt.Sprintf("%s:%g", kind, l.Cost(kind))
+		}
+		parts = append(parts, fmt.Sprintf("%s=%g/%s/%s", name, l.Burst, l.Refill, strings.Join(costs, ",")))
+	}
+	return strings.Join(parts, " ")
+}
+
+// BuildAntiSpamReport memaparkan had berkesan setiap peranan dan override masa jalan
+func BuildAntiSpamReport() string {
+	spamSettings.Lock()
+	defer spamSettings.Unlock()
+	var sb strings.Builder
+	sb.WriteString("🛡️ TETAPAN ANTI-SPAM\n")
+	for _, role := range allRoles {
+		l, ok := spamSettings.limits[role]
+		if !ok {
+			continue
+		}
+		if !l.Enabled {
+			sb.WriteString(fmt.Sprintf("\n%s: dimatikan\n", role))
+			continue
+		}
+		costs := make([]string, len(actionKinds))
+		for i, kind := range actionKinds {
+			costs[i] = fmt.Sprintf("%s %g", kind, l.Cost(kind))
+		}
+		sb.WriteString(fmt.Sprintf("\n%s: burst %g, refill %s\n   Kos: %s\n",
+			role, l.Burst, l.Refill, strings.Join(costs, ", ")))
+	}
+
+	sb.WriteString("\nOverride /antispam:\n")
+	if len(spamSettings.runtime) == 0 {
+		sb.WriteString("   tiada\n")
+	}
+	names := make([]string, 0, len(spamSettings.runtime))
+	for name := range spamSettings.runtime {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("   %s = %s\n", name, spamSettings.runtime[name]))
+	}
+	return sb.String()
+}
+
+// handleAntiSpam: /antispam | /antispam set <[peranan.]kunci> <nilai> | /antispam unset <[peranan.]kunci>
+func handleAntiSpam(ctx *CommandContext) {
+	if len(ctx.Args) == 0 {
+		ctx.Reply(BuildAntiSpamReport())
+		return
+	}
+
+	sub := strings.ToLower(ctx.Args[0])
+	var value string
+	switch {
+	case sub == "set" && len(ctx.Args) == 3:
+		value = ctx.Args[2]
+	case sub == "unset" && len(ctx.Args) == 2:
+	default:
+		ctx.Reply("⚠️ Format salah:\n" +
+			"/antispam set <[peranan.]kunci> <nilai>\n" +
+			"/antispam unset <[peranan.]kunci>\n\n" +
+			"Kunci: enabled, burst, refill, callback, text, media, command\n" +
+			"Peranan: user, support, moderator, owner\n" +
+			"Contoh: /antispam set user.media 3")
+		return
+	}
+
+	key, previous, err := SetSpamSetting(ctx.Args[1], value)
+	if err != nil {
+		ctx.Reply("❌ " + err.Error())
+		return
+	}
+	if previous == "" {
+		previous = "(konfigurasi)"
+	}
+	current := value
+	if current == "" {
+		current = "(konfigurasi)"
+	}
+	actor := StaffName(ctx.UserID, ctx.Username)
+	log.Printf("🛡️ Anti-spam %s: %s → %s oleh %s", key, previous, current, actor)
+	logAudit(AuditRecord{
+		Actor:   actor,
+		ActorID: ctx.UserID,
+		Action:  auditConfigChange,
+		Reason:  "/antispam " + strings.Join(ctx.Args, " "),
+		Detail:  fmt.Sprintf("%s: %s → %s", key, previous, current),
+	})
+	ctx.Reply(fmt.Sprintf("✅ %s: %s → %s\n\n%s", key, previous, current, BuildAntiSpamReport()))
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Status rayuan sekatan
+const (
+	appealPending  = "pending"
+	appealApproved = "approved"
+	appealRejected = "rejected"
+	appealMoreInfo = "more_info"
+)
+
+// Data callback butang keputusan rayuan: "<tindakan>:<user_id>"
+const (
+	cbAppealApprove = "rayuan_lulus"
+	cbAppealReject  = "rayuan_tolak"
+	cbAppealMore    = "rayuan_info"
+)
+
+// BanAppeal ialah rayuan user terhadap sekatan, disimpan bersama rekod sekatan
+type BanAppeal struct {
+	Status    string           `json:"status"`
+	Messages  []AppealMessage  `json:"messages"`
+	Decisions []AppealDecision `json:"decisions,omitempty"`
+}
+
+// AppealMessage ialah teks rayuan (atau maklumat tambahan) daripada user
+type AppealMessage struct {
+	Text string    `json:"text"`
+	At   time.Time `json:"at"`
+}
+
+// AppealDecision ialah satu keputusan Admin ke atas rayuan
+type AppealDecision struct {
+	Action  string    `json:"action"`
+	ByAdmin string    `json:"by_admin"`
+	At      time.Time `json:"at"`
+}
+
+// handleBannedUpdate mengendalikan update daripada user yang disekat.
+// Hanya /rayuan dan hak data peribadi (/data_saya, /tarik_persetujuan berserta butang
+// pengesahannya) diproses; semua update lain diabaikan seperti biasa.
+func handleBannedUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update, userID int64, username string) {
+	if update.CallbackQuery != nil {
+		if isWithdrawCallback(update.CallbackQuery.Data) {
+			// Tiada pintu terma selepas tarik balik: user masih disekat
+			handleWithdrawCallback(bot, update.CallbackQuery)
+		}
+		return
+	}
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+	switch update.Message.Command() {
+	case "data_saya":
+		sendUserDataExport(bot, chatID, userID)
+		return
+	case "tarik_persetujuan":
+		sendWithdrawPrompt(bot, chatID)
+		return
+	}
+	if update.Message.Command() != "rayuan" {
+		return
+	}
+	text := strings.TrimSpace(update.Message.CommandArguments())
+	if text == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Format salah: /rayuan <penjelasan anda>"))
+		return
+	}
+
+	reply, err := SubmitAppeal(bot, userID, username, text)
+	if err != nil {
+		log.Printf("Ralat rayuan user %d: %v", userID, err)
+		reply = "❌ Ralat teknikal (storan), sila cuba lagi."
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, reply))
+}
+
+// SubmitAppeal menyimpan rayuan user dalam rekod sekatan dan menghantarnya kepada Admin.
+// Setiap sekatan hanya boleh dirayu sekali; maklumat tambahan diterima jika Admin memintanya.
+func SubmitAppeal(bot *tgbotapi.BotAPI, userID int64, username string, text string) (string, error) {
+	rec, err := store.GetBan(userID)
+	if err != nil {
+		return "", err
+	}
+	if rec == nil || !rec.Active(time.Now()) {
+		return "ℹ️ Tiada sekatan aktif untuk dirayu.", nil
+	}
+
+	followUp := false
+	switch {
+	case rec.Appeal == nil:
+		rec.Appeal = &BanAppeal{}
+	case rec.Appeal.Status == appealMoreInfo:
+		followUp = true
+	case rec.Appeal.Status == appealPending:
+		return "⏳ Rayuan anda sedang disemak oleh Admin. Sila tunggu keputusan.", nil
+	default:
+		return "🚫 Rayuan untuk sekatan ini telah diputuskan dan tidak boleh dihantar semula.", nil
+	}
+
+	rec.Appeal.Status = appealPending
+	rec.Appeal.Messages = append(rec.Appeal.Messages, AppealMessage{Text: text, At: time.Now()})
+	if err := store.SaveBan(*rec); err != nil {
+		return "", err
+	}
+
+	title := "📨 RAYUAN SEKATAN BARU"
+	if followUp {
+		title = "📨 MAKLUMAT TAMBAHAN RAYUAN"
+	}
+	notifyAppeal(bot, fmt.Sprintf(
+		"%s\n\n"+
+			"👤 User: @%s\n"+
+			"🆔 ID: %d\n"+
+			"📋 Sebab sekatan: %s\n"+
+			"👮 Disekat oleh: %s\n"+
+			"⏰ Disekat pada: %s\n\n"+
+			"💬 Rayuan:\n%s",
+		title, username, userID, rec.Reason, rec.ByAdmin,
+		rec.BannedAt.Format("2006-01-02 15:04:05"), text), userID)
+
+	return "✅ Rayuan anda telah dihantar kepada Admin. Anda akan dimaklumkan tentang keputusannya.", nil
+}
+
+// notifyAppeal menghantar rayuan (teks biasa) berserta butang keputusan kepada Admin
+func notifyAppeal(bot *tgbotapi.BotAPI, text string, userID int64) {
+	markup := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Lulus", fmt.Sprintf("%s:%d", cbAppealApprove, userID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Tolak", fmt.Sprintf("%s:%d", cbAppealReject, userID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("❓ Minta Maklumat", fmt.Sprintf("%s:%d", cbAppealMore, userID)),
+		),
+	)
+	for _, id := range staffWith(PermAppeals) {
+		msg := tgbotapi.NewMessage(id, text)
+		msg.ReplyMarkup = markup
+		sendToStaff(bot, id, msg)
+	}
+}
+
+// isAppealCallback menyemak sama ada data callback ialah butang keputusan rayuan
+func isAppealCallback(data string) bool {
+	return strings.HasPrefix(data, "rayuan_")
+}
+
+// handleAppealCallback memproses butang Lulus / Tolak / Minta Maklumat daripada Admin
+func handleAppealCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	adminID := callback.From.ID
+	if !HasPermission(adminID, PermAppeals) {
+		bot.Request(tgbotapi.NewCallback(callback.ID, "Tiada kebenaran"))
+		return
+	}
+
+	action, idStr, _ := strings.Cut(callback.Data, ":")
+	targetID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		bot.Request(tgbotapi.NewCallback(callback.ID, "Data tidak sah"))
+		return
+	}
+
+	result, err := DecideAppeal(bot, adminID, targetID, action, StaffName(adminID, callback.From.UserName))
+	if err != nil {
+		log.Printf("Ralat keputusan rayuan user %d: %v", targetID, err)
+		bot.Request(tgbotapi.NewCallback(callback.ID, "❌ Ralat teknikal (storan), sila cuba lagi."))
+		return
+	}
+	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	// Tunjuk keputusan pada mesej rayuan dan buang butang
+	if callback.Message != nil {
+		edit := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID,
+			callback.Message.Text+"\n\n"+result)
+		bot.Send(edit)
+	}
+}
+
+// DecideAppeal merekod keputusan Admin dalam rekod sekatan dan memaklumkan user.
+// Memulangkan ringkasan keputusan untuk dipaparkan kepada Admin.
+func DecideAppeal(bot *tgbotapi.BotAPI, adminID int64, targetID int64, action string, byAdmin string) (string, error) {
+	rec, err := store.GetBan(targetID)
+	if err != nil {
+		return "", err
+	}
+	if rec == nil || rec.Appeal == nil {
+		return "ℹ️ Tiada rayuan untuk user ini (rekod sekatan mungkin telah dibuang).", nil
+	}
+	if rec.Appeal.Status != appealPending {
+		return fmt.Sprintf("ℹ️ Rayuan ini telah diputuskan (status: %s).", rec.Appeal.Status), nil
+	}
+	now := time.Now()
+	if !rec.Active(now) {
+		return "ℹ️ Sekatan ini tidak lagi aktif (tamat tempoh atau telah ditarik balik). Keputusan tidak direkod.", nil
+	}
+
+	var status, result, notice string
+	switch action {
+	case cbAppealApprove:
+		status = appealApproved
+		rec.LiftedAt = &now
+		result = fmt.Sprintf("✅ Diluluskan oleh %s - sekatan ditarik balik.", byAdmin)
+		notice = fmt.Sprintf(
+			"✅ *RAYUAN DILULUSKAN*\n\n"+
+				"Rayuan anda telah diluluskan dan akaun anda (ID: `%d`) telah *DINYAHSEKAT*.\n\n"+
+				"Sila taip /start untuk mula.", targetID)
+	case cbAppealReject:
+		status = appealRejected
+		result = fmt.Sprintf("❌ Ditolak oleh %s.", byAdmin)
+		notice = "❌ *RAYUAN DITOLAK*\n\nRayuan anda telah disemak dan ditolak oleh Admin. Sekatan kekal berkuat kuasa."
+	case cbAppealMore:
+		status = appealMoreInfo
+		result = fmt.Sprintf("❓ %s meminta maklumat tambahan.", byAdmin)
+		notice = "❓ *MAKLUMAT TAMBAHAN DIPERLUKAN*\n\n" +
+			"Admin memerlukan maklumat lanjut untuk menilai rayuan anda.\n" +
+			"Taip `/rayuan <maklumat tambahan>` untuk membalas."
+	default:
+		return "", fmt.Errorf("tindakan rayuan tidak dikenali: %q", action)
+	}
+
+	rec.Appeal.Status = status
+	rec.Appeal.Decisions = append(rec.Appeal.Decisions, AppealDecision{Action: status, ByAdmin: byAdmin, At: now})
+	if err := store.SaveBan(*rec); err != nil {
+		return "", err
+	}
+	if status == appealApproved {
+		clearBanState(targetID)
+		clearSpamStrikes(targetID)
+	}
+	log.Printf("⚖️ Rayuan user %d: %s oleh %s", targetID, status, byAdmin)
+	audit := AuditRecord{Actor: byAdmin, ActorID: adminID, Action: auditAppeal, Target: targetID, Detail: "rayuan " + status}
+	if status == appealApproved {
+		// Rayuan yang diluluskan menarik balik sekatan
+		audit.Action, audit.Detail = auditUnban, fmt.Sprintf("rayuan diluluskan; sekatan asal: %s (oleh %s)", rec.Reason, rec.ByAdmin)
+	}
+	logAudit(audit)
+
+	msg := tgbotapi.NewMessage(targetID, notice)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("Gagal hantar keputusan rayuan ke user %d: %v", targetID, err)
+	}
+	return result, nil
+}
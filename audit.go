@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Jenis tindakan dalam log audit
+const (
+	auditBan            = "ban"
+	auditUnban          = "unban"
+	auditBanExpired     = "ban_expired"
+	auditAppeal         = "appeal"
+	auditBroadcast      = "broadcast"
+	auditContentReload  = "content_reload"
+	auditConfigChange   = "config_change"
+	auditSourceManual   = "manual"
+	auditSourceAuto     = "auto"
+	auditSystemActor    = "Sistem"
+	auditMaxListResults = 20
+)
+
+// auditActions ialah semua tindakan yang boleh ditapis dengan /audit
+var auditActions = []string{auditBan, auditUnban, auditBanExpired, auditAppeal,
+	auditBroadcast, auditContentReload, auditConfigChange}
+
+// AuditRecord ialah satu baris dalam log audit (STATE_DIR/audit.jsonl)
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	ActorID int64     `json:"actor_id,omitempty"`
+	Action  string    `json:"action"`
+	Target  int64     `json:"target,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Source  string    `json:"source"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+var auditMu sync.Mutex
+
+func auditPath() string {
+	return filepath.Join(config.StateDir, "audit.jsonl")
+}
+
+// logAudit menambah satu rekod ke hujung log audit (append-only, fsync)
+func logAudit(rec AuditRecord) {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	if rec.Source == "" {
+		rec.Source = auditSourceManual
+	}
+	data, _ := json.Marshal(rec)
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if err := os.MkdirAll(config.StateDir, 0o755); err != nil {
+		log.Printf("⚠️ Gagal tulis log audit: %v", err)
+		return
+	}
+	f, err := os.OpenFile(auditPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Printf("⚠️ Gagal tulis log audit: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("⚠️ Gagal tulis log audit: %v", err)
+		return
+	}
+	f.Sync()
+}
+
+// auditFilter ialah tapisan /audit
+type auditFilter struct {
+	UserID int64
+	Action string
+	Since  time.Time
+}
+
+func (f auditFilter) match(rec AuditRecord) bool {
+	if f.UserID != 0 && rec.Target != f.UserID && rec.ActorID != f.UserID {
+		return false
+	}
+	if f.Action != "" && rec.Action != f.Action {
+		return false
+	}
+	return f.Since.IsZero() || !rec.Time.Before(f.Since)
+}
+
+// readAudit memulangkan semua rekod yang sepadan dengan tapisan (terlama dahulu)
+func readAudit(filter auditFilter) ([]AuditRecord, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	f, err := os.Open(auditPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal buka log audit: %v", err)
+	}
+	defer f.Close()
+
+	var recs []AuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec AuditRecord
+		if json.Unmarshal(scanner.Bytes(), &rec) != nil {
+			continue // baris separuh ditulis semasa crash
+		}
+		if filter.match(rec) {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, scanner.Err()
+}
+
+// parseAuditFilter membaca argumen /audit: ID/@username, nama tindakan,
+// dan tempoh (24h, 7d) atau tarikh (2006-01-02) sebagai "since"
+func parseAuditFilter(args []string, now time.Time) (auditFilter, error) {
+	var filter auditFilter
+	for _, arg := range args {
+		if d, ok := parseBanDuration(arg); ok {
+			filter.Since = now.Add(-d)
+			continue
+		}
+		if t, err := time.ParseInLocation("2006-01-02", arg, now.Location()); err == nil {
+			filter.Since = t
+			continue
+		}
+		if isAuditAction(arg) {
+			filter.Action = arg
+			continue
+		}
+		id, err := resolveUserRef(arg)
+		if err != nil {
+			return filter, fmt.Errorf("%v\nTindakan yang sah: %s", err, strings.Join(auditActions, ", "))
+		}
+		filter.UserID = id
+	}
+	return filter, nil
+}
+
+func isAuditAction(s string) bool {
+	for _, a := range auditActions {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (rec AuditRecord) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s [%s/%s] %s", rec.Time.Format("2006-01-02 15:04:05"), rec.Action, rec.Source, rec.Actor))
+	if rec.Target != 0 {
+		sb.WriteString(fmt.Sprintf(" → %d", rec.Target))
+	}
+	if rec.Reason != "" {
+		sb.WriteString(fmt.Sprintf("\n   Sebab: %s", rec.Reason))
+	}
+	if rec.Detail != "" {
+		sb.WriteString(fmt.Sprintf("\n   %s", rec.Detail))
+	}
+	return sb.String()
+}
+
+// handleAudit: /audit [export] [user_id|@username] [tindakan] [24h|7d|2006-01-02]
+func handleAudit(ctx *CommandContext) {
+	args := ctx.Args
+	export := len(args) > 0 && args[0] == "export"
+	if export {
+		args = args[1:]
+	}
+	filter, err := parseAuditFilter(args, time.Now())
+	if err != nil {
+		ctx.Reply("❌ " + err.Error())
+		return
+	}
+	recs, err := readAudit(filter)
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("❌ %v", err))
+		return
+	}
+
+	if export {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, rec := range recs {
+			enc.Encode(rec)
+		}
+		if len(recs) == 0 {
+			ctx.Reply("ℹ️ Tiada rekod audit yang sepadan.")
+			return
+		}
+		doc := tgbotapi.NewDocument(ctx.ChatID, tgbotapi.FileBytes{
+			Name:  fmt.Sprintf("audit_%s.jsonl", time.Now().Format("20060102_150405")),
+			Bytes: buf.Bytes(),
+		})
+		doc.Caption = fmt.Sprintf("📄 %d rekod audit", len(recs))
+		if sent, err := ctx.Bot.Send(doc); err == nil {
+			ctx.Track(sent.MessageID)
+		}
+		return
+	}
+
+	if len(recs) == 0 {
+		ctx.Reply("ℹ️ Tiada rekod audit yang sepadan.")
+		return
+	}
+	// Terbaru dahulu, dihadkan supaya muat dalam satu mesej
+	sort.SliceStable(recs, func(i, j int) bool { return recs[i].Time.After(recs[j].Time) })
+	shown := recs
+	if len(shown) > auditMaxListResults {
+		shown = shown[:auditMaxListResults]
+	}
+	blocks := []string{fmt.Sprintf("📜 LOG AUDIT (%d daripada %d rekod)", len(shown), len(recs))}
+	for _, rec := range shown {
+		blocks = append(blocks, rec.String())
+	}
+	for _, part := range splitMessages(blocks, telegramMessageLimit) {
+		ctx.Reply(part)
+	}
+}
+
+// configSnapshot ialah ringkasan tetapan berkesan (tanpa rahsia) untuk mengesan perubahan
+// konfigurasi. Had anti-spam diambil selepas override /antispam digabungkan (initAntiSpam).
+func configSnapshot(cfg Config) string {
+	staff := make([]string, len(cfg.Staff))
+	for i, m := range cfg.Staff {
+		staff[i] = fmt.Sprintf("%d:%s", m.UserID, strings.ToLower(m.Role.String()))
+	}
+	return fmt.Sprintf("storage=%s ban_policy=%s agreement_policy=%s terms_key=%t parse_mode=%q broadcast_rate=%d "+
+		"spam_limits=[%s] spam_ladder=%v admins=[%s]",
+		cfg.StorageBackend, cfg.BanLookupPolicy, cfg.AgreementLookupPolicy, len(cfg.TermsPublicKey) > 0,
+		cfg.TermsParseMode, cfg.BroadcastRate, spamLimitsSnapshot(), cfg.SpamLadder, strings.Join(staff, ","))
+}
+
+// auditConfigOnStartup merekod konfigurasi jika berbeza daripada snapshot startup yang
+// terakhir direkod (perubahan /antispam direkod berasingan sebagai rekod manual)
+func auditConfigOnStartup(cfg Config) {
+	snapshot := configSnapshot(cfg)
+	recs, err := readAudit(auditFilter{Action: auditConfigChange})
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	for i := len(recs) - 1; i >= 0; i-- {
+		if recs[i].Source != auditSourceAuto {
+			continue
+		}
+		if recs[i].Detail == snapshot {
+			return
+		}
+		break
+	}
+	logAudit(AuditRecord{
+		Actor:  auditSystemActor,
+		Action: auditConfigChange,
+		Source: auditSourceAuto,
+		Reason: "Konfigurasi dimuatkan semasa startup",
+		Detail: snapshot,
+	})
+}
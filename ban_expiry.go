@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// parseBanDuration membaca tempoh sekatan seperti "30m", "24h" atau "7d"
+func parseBanDuration(s string) (time.Duration, bool) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, false
+		}
+		return time.Duration(days) * 24 * time.Hour, true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// startBanExpiry menyemak sekatan sementara secara berkala. Sekatan yang tamat ditanda
+// ditarik balik dalam storan, dilog dan user dimaklumkan.
+func startBanExpiry(bot *tgbotapi.BotAPI, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			expireBans(bot, time.Now())
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// expireBans menarik balik semua sekatan sementara yang telah tamat pada masa now
+func expireBans(bot *tgbotapi.BotAPI, now time.Time) {
+	bans, err := store.ListBans()
+	if err != nil {
+		log.Printf("⚠️ Gagal senaraikan sekatan untuk semakan tamat tempoh: %v", err)
+		return
+	}
+	for _, rec := range bans {
+		if rec.LiftedAt != nil || !rec.Expired(now) {
+			continue
+		}
+		// Cuba semula pada pusingan seterusnya jika gagal
+		if err := liftBan(rec, now); err != nil {
+			log.Printf("⚠️ Gagal tamatkan sekatan user %d: %v", rec.UserID, err)
+			continue
+		}
+		log.Printf("⏰ Sekatan sementara user %d tamat (disekat %s oleh %s, tamat %s)",
+			rec.UserID, rec.BannedAt.Format("2006-01-02 15:04:05"), rec.ByAdmin,
+			rec.ExpiresAt.Format("2006-01-02 15:04:05"))
+		logAudit(AuditRecord{Actor: auditSystemActor, Action: auditBanExpired, Target: rec.UserID, Reason: rec.Reason,
+			Source: auditSourceAuto, Detail: fmt.Sprintf("disekat oleh %s, tamat %s", rec.ByAdmin, rec.ExpiresAt.Format("2006-01-02 15:04:05"))})
+
+		notis := fmt.Sprintf(
+			"✅ *SEKATAN TELAH TAMAT*\n\n"+
+				"Tempoh sekatan sementara akaun anda (ID: `%d`) telah tamat.\n\n"+
+				"Anda kini boleh menggunakan bot semula. Sila taip /start untuk mula.", rec.UserID)
+		sendLiftNotice(bot, rec.UserID, notis)
+	}
+}
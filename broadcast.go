@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Data callback butang siaran
+const (
+	cbBroadcastSend   = "bc_hantar"
+	cbBroadcastCancel = "bc_batal"
+	cbBroadcastStop   = "bc_henti"
+)
+
+// Had Telegram untuk kapsyen gambar (mesej teks dihadkan oleh telegramMessageLimit)
+const telegramCaptionLimit = 1024
+
+// broadcastProgressEvery ialah selang minimum antara suntingan mesej status (had per-chat Telegram)
+const broadcastProgressEvery = 3 * time.Second
+
+// broadcastDraft ialah siaran yang sedang dipratonton oleh Admin
+type broadcastDraft struct {
+	Text    string
+	PhotoID string
+	Buttons [][]tgbotapi.InlineKeyboardButton
+}
+
+// broadcastJob ialah siaran yang sedang dihantar
+type broadcastJob struct {
+	adminID int64
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func (j *broadcastJob) cancel() {
+	j.once.Do(func() { close(j.stop) })
+}
+
+// broadcasts menyimpan draf setiap Admin dan siaran yang sedang berjalan (satu pada satu masa)
+var broadcasts = struct {
+	sync.Mutex
+	drafts  map[int64]*broadcastDraft
+	running *broadcastJob
+}{drafts: make(map[int64]*broadcastDraft)}
+
+// parseBroadcast memisahkan teks siaran dan baris butang "btn: Label | https://url"
+func parseBroadcast(args string) (text string, buttons [][]tgbotapi.InlineKeyboardButton, err error) {
+	var lines []string
+	for _, line := range strings.Split(args, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(strings.ToLower(trimmed), "btn:") {
+			lines = append(lines, line)
+			continue
+		}
+		label, url, ok := strings.Cut(trimmed[len("btn:"):], "|")
+		label, url = strings.TrimSpace(label), strings.TrimSpace(url)
+		if !ok || label == "" || !(strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")) {
+			return "", nil, fmt.Errorf("butang tidak sah: %q (format: btn: Label | https://url)", trimmed)
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL(label, url)))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), buttons, nil
+}
+
+// message membina mesej siaran untuk satu penerima (satu mesej sahaja setiap penerima)
+func (d *broadcastDraft) message(chatID int64) tgbotapi.Chattable {
+	var markup interface{}
+	if len(d.Buttons) > 0 {
+		markup = tgbotapi.NewInlineKeyboardMarkup(d.Buttons...)
+	}
+	if d.PhotoID != "" {
+		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileID(d.PhotoID))
+		photo.Caption = d.Text
+		photo.ReplyMarkup = markup
+		return photo
+	}
+	msg := tgbotapi.NewMessage(chatID, d.Text)
+	msg.ReplyMarkup = markup
+	return msg
+}
+
+// broadcastRecipients memulangkan semua user yang bersetuju, masih aktif dan tidak disekat
+func broadcastRecipients() ([]int64, error) {
+	recs, err := store.ListAgreements()
+	if err != nil {
+		return nil, err
+	}
+	bans, err := store.ListBans()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	banned := make(map[int64]bool)
+	for _, b := range bans {
+		if b.Active(now) {
+			banned[b.UserID] = true
+		}
+	}
+	var ids []int64
+	for _, r := range recs {
+		if r.InactiveSince == nil && !banned[r.UserID] {
+			ids = append(ids, r.UserID)
+		}
+	}
+	return ids, nil
+}
+
+// handleBroadcast: /broadcast <teks> (balas kepada gambar untuk siaran bergambar)
+func handleBroadcast(ctx *CommandContext) {
+	broadcasts.Lock()
+	busy := broadcasts.running != nil
+	broadcasts.Unlock()
+	if busy {
+		ctx.Reply("⏳ Siaran lain sedang dihantar. Sila tunggu atau hentikannya dahulu.")
+		return
+	}
+
+	text, buttons, err := parseBroadcast(ctx.Message.CommandArguments())
+	if err != nil {
+		ctx.Reply("❌ " + err.Error())
+		return
+	}
+	draft := &broadcastDraft{Text: text, Buttons: buttons}
+	if reply := ctx.Message.ReplyToMessage; reply != nil && len(reply.Photo) > 0 {
+		draft.PhotoID = reply.Photo[len(reply.Photo)-1].FileID
+		if draft.Text == "" {
+			draft.Text = reply.Caption
+		}
+	}
+
+	switch {
+	case draft.Text == "" && draft.PhotoID == "":
+		ctx.Reply("⚠️ Format salah: /broadcast <teks>\n" +
+			"Butang (pilihan, satu baris setiap butang): btn: Label | https://url\n" +
+			"Untuk siaran bergambar, balas (reply) kepada gambar dengan /broadcast.")
+		return
+	case draft.PhotoID != "" && textLength(draft.Text) > telegramCaptionLimit:
+		ctx.Reply(fmt.Sprintf("❌ Kapsyen gambar melebihi %d aksara.", telegramCaptionLimit))
+		return
+	case textLength(draft.Text) > telegramMessageLimit:
+		ctx.Reply(fmt.Sprintf("❌ Mesej melebihi %d aksara.", telegramMessageLimit))
+		return
+	}
+
+	recipients, err := broadcastRecipients()
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("❌ Gagal senaraikan penerima: %v", err))
+		return
+	}
+
+	// Pratonton: Admin melihat mesej tepat seperti yang akan diterima user
+	ctx.Reply("👁️ PRATONTON SIARAN")
+	preview, err := ctx.Bot.Send(draft.message(ctx.ChatID))
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("❌ Pratonton gagal (semak butang/gambar): %v", err))
+		return
+	}
+	ctx.Track(preview.MessageID)
+
+	broadcasts.Lock()
+	broadcasts.drafts[ctx.UserID] = draft
+	broadcasts.Unlock()
+
+	confirm := tgbotapi.NewMessage(ctx.ChatID, fmt.Sprintf("📢 Hantar siaran di atas kepada %d pengguna?", len(recipients)))
+	confirm.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Hantar", cbBroadcastSend),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Batal", cbBroadcastCancel),
+		),
+	)
+	if sent, err := ctx.Bot.Send(confirm); err == nil {
+		ctx.Track(sent.MessageID)
+	}
+}
+
+// isBroadcastCallback menyemak sama ada data callback ialah butang siaran
+func isBroadcastCallback(data string) bool {
+	return strings.HasPrefix(data, "bc_")
+}
+
+// handleBroadcastCallback memproses butang Hantar / Batal / Henti
+func handleBroadcastCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	adminID := callback.From.ID
+	if !HasPermission(adminID, PermBroadcast) || callback.Message == nil {
+		bot.Request(tgbotapi.NewCallback(callback.ID, "Tiada kebenaran"))
+		return
+	}
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+
+	switch callback.Data {
+	case cbBroadcastCancel:
+		broadcasts.Lock()
+		delete(broadcasts.drafts, adminID)
+		broadcasts.Unlock()
+		bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "❌ Siaran dibatalkan."))
+		bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	case cbBroadcastStop:
+		broadcasts.Lock()
+		job := broadcasts.running
+		broadcasts.Unlock()
+		if job != nil {
+			job.cancel()
+		}
+		bot.Request(tgbotapi.NewCallback(callback.ID, "Siaran dihentikan"))
+
+	case cbBroadcastSend:
+		broadcasts.Lock()
+		draft := broadcasts.drafts[adminID]
+		if draft == nil || broadcasts.running != nil {
+			broadcasts.Unlock()
+			bot.Request(tgbotapi.NewCallback(callback.ID, "Tiada draf, atau siaran lain sedang berjalan"))
+			return
+		}
+		delete(broadcasts.drafts, adminID)
+		job := &broadcastJob{adminID: adminID, stop: make(chan struct{})}
+		broadcasts.running = job
+		broadcasts.Unlock()
+		bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+		recipients, err := broadcastRecipients()
+		if err != nil {
+			broadcasts.Lock()
+			broadcasts.running = nil
+			broadcasts.Unlock()
+			bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf("❌ Gagal senaraikan penerima: %v", err)))
+			return
+		}
+		log.Printf("📢 Siaran dimulakan oleh %s kepada %d pengguna", StaffName(adminID, callback.From.UserName), len(recipients))
+		go runBroadcast(bot, job, draft, recipients, chatID, messageID)
+
+	default:
+		bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	}
+}
+
+// broadcastProgress ialah kiraan semasa siaran
+type broadcastProgress struct {
+	total, sent, inactive, failed int
+}
+
+func (p broadcastProgress) String() string {
+	return fmt.Sprintf("Terhantar: %d/%d\nTidak aktif (block/nyahaktif): %d\nGagal: %d",
+		p.sent, p.total, p.inactive, p.failed)
+}
+
+// runBroadcast menghantar siaran dalam had kadar global (BROADCAST_RATE mesej/saat).
+// Setiap penerima hanya menerima satu mesej, jadi had per-chat hanya terpakai kepada
+// mesej status Admin yang disunting paling kerap sekali setiap broadcastProgressEvery.
+func runBroadcast(bot *tgbotapi.BotAPI, job *broadcastJob, draft *broadcastDraft, recipients []int64, statusChat int64, statusMsg int) {
+	defer func() {
+		broadcasts.Lock()
+		broadcasts.running = nil
+		broadcasts.Unlock()
+	}()
+
+	stopMarkup := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("⛔ Henti", cbBroadcastStop)),
+	)
+	progress := broadcastProgress{total: len(recipients)}
+	showProgress := func() {
+		edit := tgbotapi.NewEditMessageTextAndMarkup(statusChat, statusMsg,
+			"📤 Siaran sedang dihantar...\n\n"+progress.String(), stopMarkup)
+		bot.Send(edit)
+	}
+	showProgress()
+
+	ticker := time.NewTicker(time.Second / time.Duration(config.BroadcastRate))
+	defer ticker.Stop()
+	lastEdit := time.Now()
+	cancelled := false
+
+loop:
+	for _, userID := range recipients {
+		select {
+		case <-job.stop:
+			cancelled = true
+			break loop
+		case <-ticker.C:
+		}
+
+		err := sendWithRetry(bot, draft.message(userID), job.stop)
+		switch {
+		case err == nil:
+			progress.sent++
+		case isInactiveRecipient(err):
+			progress.inactive++
+			if merr := setRecipientInactive(userID, true); merr != nil {
+				log.Printf("⚠️ Gagal tanda user %d tidak aktif: %v", userID, merr)
+			}
+		default:
+			progress.failed++
+			log.Printf("⚠️ Siaran ke user %d gagal: %v", userID, err)
+		}
+
+		if time.Since(lastEdit) >= broadcastProgressEvery {
+			showProgress()
+			lastEdit = time.Now()
+		}
+	}
+
+	title := "✅ Siaran selesai."
+	if cancelled {
+		title = "⛔ Siaran dihentikan oleh Admin."
+	}
+	bot.Send(tgbotapi.NewEditMessageText(statusChat, statusMsg, title+"\n\n"+progress.String()))
+	log.Printf("📢 %s %s", title, strings.ReplaceAll(progress.String(), "\n", ", "))
+
+	preview := []rune(draft.Text)
+	if len(preview) > 100 {
+		preview = append(preview[:100], '…')
+	}
+	logAudit(AuditRecord{
+		Actor:   StaffName(job.adminID, ""),
+		ActorID: job.adminID,
+		Action:  auditBroadcast,
+		Reason:  string(preview),
+		Detail:  title + " " + strings.ReplaceAll(progress.String(), "\n", ", "),
+	})
+}
+
+// sendWithRetry menghantar mesej dan menunggu jika Telegram membalas 429 (retry_after)
+func sendWithRetry(bot *tgbotapi.BotAPI, msg tgbotapi.Chattable, stop <-chan struct{}) error {
+	for attempt := 0; ; attempt++ {
+		_, err := bot.Send(msg)
+		var tgErr *tgbotapi.Error
+		if err == nil || !errors.As(err, &tgErr) || tgErr.RetryAfter <= 0 || attempt >= 3 {
+			return err
+		}
+		select {
+		case <-stop:
+			return err
+		case <-time.After(time.Duration(tgErr.RetryAfter) * time.Second):
+		}
+	}
+}
+
+// isInactiveRecipient: user telah block bot, akaun dinyahaktif atau chat tidak wujud
+func isInactiveRecipient(err error) bool {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		return false
+	}
+	return tgErr.Code == 403 ||
+		(tgErr.Code == 400 && strings.Contains(strings.ToLower(tgErr.Message), "chat not found"))
+}
+
+// setRecipientInactive menanda (atau membuang tanda) user tidak aktif dalam rekod persetujuan
+func setRecipientInactive(userID int64, inactive bool) error {
+	rec, err := store.GetAgreement(userID)
+	if err != nil || rec == nil {
+		return err
+	}
+	if (rec.InactiveSince != nil) == inactive {
+		return nil
+	}
+	if inactive {
+		now := time.Now()
+		rec.InactiveSince = &now
+	} else {
+		rec.InactiveSince = nil
+	}
+	return store.SaveAgreement(*rec)
+}
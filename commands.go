@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// CommandContext ialah maklumat yang diterima oleh setiap pengendali arahan
+type CommandContext struct {
+	Bot      *tgbotapi.BotAPI
+	Message  *tgbotapi.Message
+	UserID   int64
+	ChatID   int64
+	Username string
+	Role     Role
+	Cmd      *Command
+	Args     []string
+
+	messageIDs *map[int64][]int
+	mu         *sync.Mutex
+}
+
+// Track merekod mesej supaya dipadam oleh "♻️ Reset Mesej"
+func (c *CommandContext) Track(messageID int) {
+	addMessageID(c.messageIDs, c.mu, c.ChatID, messageID)
+}
+
+// Reply menghantar mesej teks biasa ke chat semasa
+func (c *CommandContext) Reply(text string) {
+	sent, err := c.Bot.Send(tgbotapi.NewMessage(c.ChatID, text))
+	if err == nil {
+		c.Track(sent.MessageID)
+	}
+}
+
+// ReplyMarkdown menghantar mesej Markdown ke chat semasa
+func (c *CommandContext) ReplyMarkdown(text string) {
+	msg := tgbotapi.NewMessage(c.ChatID, text)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	sent, err := c.Bot.Send(msg)
+	if err == nil {
+		c.Track(sent.MessageID)
+	}
+}
+
+// Command ialah satu arahan berdaftar
+type Command struct {
+	Name    string // tanpa "/"
+	Args    string // contoh: "<user_id>"
+	MinArgs int
+	Perm    Permission // kebenaran yang diperlukan
+	Help    string
+	// Public: boleh digunakan sebelum user bersetuju dengan Terma
+	Public  bool
+	Handler func(ctx *CommandContext)
+}
+
+// CommandRouter menghala mesej arahan ke pengendali yang berdaftar
+type CommandRouter struct {
+	commands map[string]*Command
+}
+
+func newCommandRouter() *CommandRouter {
+	return &CommandRouter{commands: make(map[string]*Command)}
+}
+
+// Register mendaftarkan satu arahan
+func (r *CommandRouter) Register(cmd Command) {
+	r.commands[cmd.Name] = &cmd
+}
+
+// Lookup memulangkan arahan mengikut nama
+func (r *CommandRouter) Lookup(name string) (*Command, bool) {
+	cmd, ok := r.commands[name]
+	return cmd, ok
+}
+
+// Dispatch menjalankan arahan dalam ctx.Message. Memulangkan false jika mesej bukan arahan.
+func (r *CommandRouter) Dispatch(ctx *CommandContext) bool {
+	if !ctx.Message.IsCommand() {
+		return false
+	}
+	name := ctx.Message.Command()
+	cmd, ok := r.commands[name]
+	if !ok || !ctx.Role.Can(cmd.Perm) {
+		// Arahan pentadbir tidak didedahkan kepada user tanpa kebenaran
+		ctx.Reply("❓ Arahan tidak dikenali. Taip /help untuk senarai arahan.")
+		return true
+	}
+
+	if !cmd.Public && !ctx.Role.Can(PermBypassChecks) && !AgreedWithPolicy(ctx.UserID) {
+		ctx.Reply(accessDeniedText(ctx.UserID))
+		return true
+	}
+
+	ctx.Cmd = cmd
+	ctx.Args = strings.Fields(ctx.Message.CommandArguments())
+	if len(ctx.Args) < cmd.MinArgs {
+		ctx.Reply(fmt.Sprintf("⚠️ Format salah: %s", cmd.Usage()))
+		return true
+	}
+
+	cmd.Handler(ctx)
+	return true
+}
+
+// Usage memulangkan contoh penggunaan arahan
+func (c *Command) Usage() string {
+	if c.Args == "" {
+		return "/" + c.Name
+	}
+	return "/" + c.Name + " " + c.Args
+}
+
+// HelpFor menyenaraikan arahan yang boleh digunakan oleh peranan tersebut
+func (r *CommandRouter) HelpFor(role Role) string {
+	var cmds []*Command
+	for _, cmd := range r.commands {
+		if role.Can(cmd.Perm) {
+			cmds = append(cmds, cmd)
+		}
+	}
+	sort.Slice(cmds, func(i, j int) bool {
+		if ri, rj := cmds[i].Perm.MinRole(), cmds[j].Perm.MinRole(); ri != rj {
+			return ri < rj
+		}
+		return cmds[i].Name < cmds[j].Name
+	})
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📖 Senarai Arahan (%s)\n", role))
+	current := Role(-1)
+	for _, cmd := range cmds {
+		if cmd.Perm.MinRole() != current {
+			current = cmd.Perm.MinRole()
+			sb.WriteString(fmt.Sprintf("\n— %s —\n", current))
+		}
+		sb.WriteString(fmt.Sprintf("%s\n   %s\n", cmd.Usage(), cmd.Help))
+	}
+	return sb.String()
+}
+
+// accessDeniedText ialah mesej untuk user yang belum (atau perlu semula) bersetuju dengan Terma
+func accessDeniedText(userID int64) string {
+	if ReconsentVersion(userID) != "" {
+		return "📢 Terma & Syarat telah dikemaskini. Sila taip /start untuk membaca dan bersetuju semula."
+	}
+	return "⚠️ Akses dihadkan. Sila taip /start."
+}
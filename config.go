@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config menyimpan tetapan bot yang dibaca dari environment semasa startup
+type Config struct {
+	// StorageBackend: "github" (asal), "fs" atau "sqlite"
+	StorageBackend string
+	// DataDir ialah folder akar untuk backend "fs" (mengandungi agreements/ & blacklist/)
+	DataDir    string
+	SQLitePath string
+
+	GithubToken  string
+	GithubRepo   string
+	GithubBranch string
+	GithubAPIURL string
+	// GithubSyncInterval: kekerapan penyegaran indeks agreements/ & blacklist/ (0 = tiada indeks)
+	GithubSyncInterval time.Duration
+
+	// StateDir ialah folder untuk fail keadaan tempatan bot (jurnal tulisan, dll.)
+	StateDir string
+	// WriteMaxAttempts: bilangan cubaan sebelum tulisan dianggap gagal kekal
+	WriteMaxAttempts int
+
+	// Polisi apabila carian storan gagal: "closed", "open" atau "last_known"
+	BanLookupPolicy       string
+	AgreementLookupPolicy string
+	// LastKnownMax: had user dalam nilai terakhir polisi last_known (LRU); LastKnownTTL:
+	// umur maksimum nilai terakhir sebelum dibuang oleh pembersih
+	LastKnownMax int
+	LastKnownTTL time.Duration
+
+	// TermsPublicKey: kunci awam ed25519 untuk mengesahkan integrity_check terms.json
+	// (kosong = pengesahan dimatikan)
+	TermsPublicKey ed25519.PublicKey
+
+	// TermsFile: salinan tempatan terms.json jika URL tidak dapat dicapai
+	TermsFile string
+	// TermsRefreshInterval: kekerapan semakan bersyarat terms.json di latar belakang
+	TermsRefreshInterval time.Duration
+
+	// TermsParseMode: parse mode Telegram untuk paparan terma ("Markdown", "MarkdownV2", "HTML" atau "")
+	TermsParseMode string
+
+	// TTL cache carian persetujuan/sekatan: positif (rekod wujud) & negatif (tiada rekod)
+	CachePositiveTTL time.Duration
+	CacheNegativeTTL time.Duration
+
+	// BanExpiryInterval: kekerapan semakan sekatan sementara yang telah tamat (0 = matikan)
+	BanExpiryInterval time.Duration
+
+	// StatsFlushInterval: kekerapan kaunter /stats disimpan ke STATE_DIR/stats.json
+	StatsFlushInterval time.Duration
+
+	// MetricsAddr: alamat pelayan metrik expvar (/debug/vars), berasingan daripada port
+	// health check awam ("off" = matikan)
+	MetricsAddr string
+
+	// BroadcastRate: had global mesej siaran sesaat (had Telegram ~30/saat)
+	BroadcastRate int
+
+	// Anti-spam (token bucket): SpamBurst token penuh, satu token diisi semula setiap
+	// SpamRefill; setiap jenis tindakan menolak SpamCosts token
+	SpamBurst  int
+	SpamRefill time.Duration
+	SpamCosts  map[ActionKind]float64
+	// SpamOverrides: override had mengikut peranan/jenis tindakan ("user.media=3,support.enabled=on")
+	SpamOverrides map[string]string
+	// SpamMaxTracked: had user yang dijejak anti-spam (LRU); SpamJanitorInterval:
+	// kekerapan pembersihan keadaan anti-spam yang lapuk (0 = matikan)
+	SpamMaxTracked      int
+	SpamJanitorInterval time.Duration
+	// SpamLadder ialah tangga hukuman bagi kesalahan spam berulang dalam SpamLookback
+	SpamLadder   []penaltyStep
+	SpamLookback time.Duration
+	// SpamStrikeRetention: tempoh sejarah kesalahan spam disimpan dalam storan untuk semakan
+	SpamStrikeRetention time.Duration
+
+	// Staff ialah pasukan moderasi (owner, moderator, support)
+	Staff []StaffMember
+}
+
+var config Config
+
+// loadConfig membaca konfigurasi dari environment variable dengan nilai lalai yang selamat
+func loadConfig() Config {
+	return Config{
+		StorageBackend: strings.ToLower(envOr("STORAGE_BACKEND", "github")),
+		DataDir:        envOr("DATA_DIR", "."),
+		SQLitePath:     envOr("SQLITE_PATH", "cryptorian.db"),
+		GithubToken:    os.Getenv("GITHUB_TOKEN"),
+		GithubRepo:     envOr("GITHUB_REPO", "Lilmoki91/CRYPTORIAN-TELEBOT"),
+		GithubBranch:   envOr("GITHUB_BRANCH", "main"),
+		GithubAPIURL:   envOr("GITHUB_API_URL", "https://api.github.com"),
+
+		GithubSyncInterval: envDuration("GITHUB_SYNC_INTERVAL", time.Minute),
+
+		StateDir:         envOr("STATE_DIR", "state"),
+		WriteMaxAttempts: envInt("WRITE_MAX_ATTEMPTS", 8),
+
+		BanLookupPolicy:       envPolicy("BAN_LOOKUP_POLICY", policyFailClosed),
+		AgreementLookupPolicy: envPolicy("AGREEMENT_LOOKUP_POLICY", policyLastKnown),
+		LastKnownMax:          envInt("LAST_KNOWN_MAX", 10000),
+		LastKnownTTL:          envLongDuration("LAST_KNOWN_TTL", 24*time.Hour),
+
+		TermsPublicKey: envPublicKey("TERMS_PUBLIC_KEY"),
+
+		TermsFile:            envOr("TERMS_FILE", "terms.json"),
+		TermsRefreshInterval: envDuration("TERMS_REFRESH_INTERVAL", 15*time.Minute),
+
+		TermsParseMode: envParseMode("TERMS_PARSE_MODE", "Markdown"),
+
+		CachePositiveTTL: envDuration("CACHE_POSITIVE_TTL", 10*time.Minute),
+		CacheNegativeTTL: envDuration("CACHE_NEGATIVE_TTL", 30*time.Second),
+
+		BanExpiryInterval: envDuration("BAN_EXPIRY_INTERVAL", time.Minute),
+
+		StatsFlushInterval: envDuration("STATS_FLUSH_INTERVAL", time.Minute),
+
+		MetricsAddr: envOr("METRICS_ADDR", "127.0.0.1:9090"),
+
+		BroadcastRate: envInt("BROADCAST_RATE", 25),
+
+		SpamBurst:  envInt("SPAM_BURST", 6),
+		SpamRefill: envDuration("SPAM_REFILL", time.Second),
+		SpamCosts:  envActionCosts("SPAM_COSTS"),
+
+		SpamOverrides: parseSpamOverrides(os.Getenv("SPAM_OVERRIDES")),
+
+		SpamMaxTracked:      envInt("SPAM_MAX_TRACKED", 10000),
+		SpamJanitorInterval: envDuration("SPAM_JANITOR_INTERVAL", time.Minute),
+
+		SpamLadder:   envPenaltyLadder("SPAM_LADDER"),
+		SpamLookback: envLongDuration("SPAM_LOOKBACK", 7*24*time.Hour),
+
+		SpamStrikeRetention: envLongDuration("SPAM_STRIKE_RETENTION", 30*24*time.Hour),
+
+		Staff: parseStaff(envOr("ADMINS", "7348614053:owner:Mr JOHAN")),
+	}
+}
+
+// envOr memulangkan nilai environment variable atau nilai lalai jika kosong
+func envOr(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// envDuration membaca tempoh (contoh "30s", "10m"); nilai tidak sah diganti dengan lalai
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("⚠️ %s tidak sah (%q), guna lalai %s", key, v, fallback)
+		return fallback
+	}
+	return d
+}
+
+// envInt membaca nombor bulat positif; nilai tidak sah diganti dengan lalai
+func envInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("⚠️ %s tidak sah (%q), guna lalai %d", key, v, fallback)
+		return fallback
+	}
+	return n
+}
+
+// envPolicy membaca polisi carian storan; nilai tidak dikenali diganti dengan lalai
+func envPolicy(key, fallback string) string {
+	v := strings.ToLower(os.Getenv(key))
+	switch v {
+	case "":
+		return fallback
+	case policyFailClosed, policyFailOpen, policyLastKnown:
+		return v
+	}
+	log.Printf("⚠️ %s tidak sah (%q), guna lalai %s", key, v, fallback)
+	return fallback
+}
+
+// envPublicKey membaca kunci awam ed25519 (base64/hex); kunci tidak sah dilog dan diabaikan
+func envPublicKey(key string) ed25519.PublicKey {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	raw, err := parseTermsKey(v)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		log.Printf("⚠️ %s tidak sah, pengesahan terma dimatikan", key)
+		return nil
+	}
+	return ed25519.PublicKey(raw)
+}
+
+// envActionCosts membaca kos anti-spam setiap jenis tindakan; nilai tidak sah diganti dengan lalai
+func envActionCosts(key string) map[ActionKind]float64 {
+	costs, err := parseActionCosts(os.Getenv(key))
+	if err != nil {
+		log.Printf("⚠️ %s tidak sah (%v), guna kos lalai", key, err)
+		costs, _ = parseActionCosts("")
+	}
+	return costs
+}
+
+// envPenaltyLadder membaca tangga hukuman anti-spam; nilai tidak sah diganti dengan lalai
+func envPenaltyLadder(key string) []penaltyStep {
+	v := os.Getenv(key)
+	if v == "" {
+		v = defaultPenaltyLadder
+	}
+	ladder, err := parsePenaltyLadder(v)
+	if err != nil {
+		log.Printf("⚠️ %s tidak sah (%v), guna lalai %s", key, err, defaultPenaltyLadder)
+		ladder, _ = parsePenaltyLadder(defaultPenaltyLadder)
+	}
+	return ladder
+}
+
+// envLongDuration seperti envDuration tetapi turut menerima hari ("7d")
+func envLongDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, ok := parseBanDuration(v)
+	if !ok {
+		log.Printf("⚠️ %s tidak sah (%q), guna lalai %s", key, v, fallback)
+		return fallback
+	}
+	return d
+}
+
+// envParseMode membaca parse mode Telegram; "none" bermaksud teks biasa
+func envParseMode(key, fallback string) string {
+	v := os.Getenv(key)
+	switch strings.ToLower(v) {
+	case "":
+		return fallback
+	case "markdown":
+		return "Markdown"
+	case "markdownv2":
+		return "MarkdownV2"
+	case "html":
+		return "HTML"
+	case "none", "plain":
+		return ""
+	}
+	log.Printf("⚠️ %s tidak sah (%q), guna lalai %s", key, v, fallback)
+	return fallback
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// registerCommands mendaftarkan semua arahan bot
+func registerCommands(r *CommandRouter) {
+	r.Register(Command{
+		Name:    "start",
+		Perm:    PermUse,
+		Public:  true,
+		Help:    "Mula / kembali ke menu utama",
+		Handler: handleStart,
+	})
+	r.Register(Command{
+		Name:   "help",
+		Perm:   PermUse,
+		Public: true,
+		Help:   "Senarai arahan yang tersedia",
+		Handler: func(ctx *CommandContext) {
+			ctx.Reply(r.HelpFor(ctx.Role))
+		},
+	})
+	r.Register(Command{
+		Name:    "data_saya",
+		Perm:    PermUse,
+		Public:  true,
+		Help:    "Muat turun semua data yang disimpan tentang anda (JSON)",
+		Handler: handleDataSaya,
+	})
+	r.Register(Command{
+		Name:    "tarik_persetujuan",
+		Perm:    PermUse,
+		Public:  true,
+		Help:    "Tarik balik persetujuan Terma & padam rekod persetujuan",
+		Handler: handleTarikPersetujuan,
+	})
+	r.Register(Command{
+		Name:    "ban",
+		Args:    "<user_id|@username> [tempoh: 30m/24h/7d] [sebab]",
+		Perm:    PermBan,
+		Help:    "Sekat user secara manual",
+		Handler: handleBan,
+	})
+	r.Register(Command{
+		Name:    "broadcast",
+		Args:    "<teks>",
+		Perm:    PermBroadcast,
+		Help:    "Siaran kepada semua user yang bersetuju (pratonton & sahkan dahulu)",
+		Handler: handleBroadcast,
+	})
+	r.Register(Command{
+		Name:    "whois",
+		Args:    "<user_id|@username>",
+		Perm:    PermWhois,
+		Help:    "Semua maklumat tentang user, dengan butang sekat/buang sekatan/mesej",
+		Handler: handleWhois,
+	})
+	r.Register(Command{
+		Name: "stats",
+		Perm: PermStats,
+		Help: "Statistik penggunaan bot",
+		Handler: func(ctx *CommandContext) {
+			ctx.Reply(BuildStatsReport(time.Now()))
+		},
+	})
+	r.Register(Command{
+		Name:    "unban",
+		Args:    "<user_id|@username>",
+		Perm:    PermUnban,
+		Help:    "Buang sekatan user",
+		Handler: handleUnban,
+	})
+	r.Register(Command{
+		Name:    "antispam",
+		Args:    "[set <[peranan.]kunci> <nilai> | unset <[peranan.]kunci>]",
+		Perm:    PermAntiSpam,
+		Help:    "Papar / tukar had anti-spam (disimpan & diaudit)",
+		Handler: handleAntiSpam,
+	})
+	r.Register(Command{
+		Name:    "audit",
+		Args:    "[export] [user_id|@username] [tindakan] [24h|7d|2006-01-02]",
+		Perm:    PermAudit,
+		Help:    "Log audit tindakan Admin & sistem (export: fail JSONL)",
+		Handler: handleAudit,
+	})
+}
+
+func handleStart(ctx *CommandContext) {
+	countStat(statStart)
+	// User yang kembali selepas block bot diaktifkan semula untuk siaran
+	if err := setRecipientInactive(ctx.UserID, false); err != nil {
+		log.Printf("⚠️ Gagal aktifkan semula user %d: %v", ctx.UserID, err)
+	}
+	isAllowed := ctx.Role.Can(PermBypassChecks) || AgreedWithPolicy(ctx.UserID)
+	sendMainMenu(ctx.Bot, ctx.ChatID, ctx.UserID, isAllowed, ctx.messageIDs, ctx.mu)
+}
+
+func handleTarikPersetujuan(ctx *CommandContext) {
+	if sent, err := sendWithdrawPrompt(ctx.Bot, ctx.ChatID); err == nil {
+		ctx.Track(sent.MessageID)
+	}
+}
+
+// sendWithdrawPrompt meminta pengesahan sebelum persetujuan ditarik balik
+func sendWithdrawPrompt(bot *tgbotapi.BotAPI, chatID int64) (tgbotapi.Message, error) {
+	msg := tgbotapi.NewMessage(chatID,
+		"⚠️ *TARIK BALIK PERSETUJUAN*\n\n"+
+			"Rekod persetujuan anda akan dipadam dan anda perlu bersetuju semula dengan Terma sebelum menggunakan bot.\n\n"+
+			"Teruskan?")
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Ya, tarik balik", "tarik_tnc_ya"),
+			tgbotapi.NewInlineKeyboardButtonData("Batal", "tarik_tnc_batal"),
+		),
+	)
+	return bot.Send(msg)
+}
+
+// isWithdrawCallback menyemak jika data butang milik pengesahan /tarik_persetujuan
+func isWithdrawCallback(data string) bool {
+	return data == "tarik_tnc_ya" || data == "tarik_tnc_batal"
+}
+
+// handleWithdrawCallback mengendalikan butang pengesahan /tarik_persetujuan.
+// Memulangkan true jika persetujuan berjaya ditarik balik.
+func handleWithdrawCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) bool {
+	chatID, messageID := callback.Message.Chat.ID, callback.Message.MessageID
+	defer bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	if callback.Data == "tarik_tnc_batal" {
+		bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "👍 Dibatalkan. Persetujuan anda kekal."))
+		return false
+	}
+	if err := WithdrawAgreement(callback.From.ID); err != nil {
+		log.Printf("Ralat tarik persetujuan user %d: %v", callback.From.ID, err)
+		bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "❌ Ralat teknikal (storan), sila cuba lagi."))
+		return false
+	}
+	bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "✅ Persetujuan anda telah ditarik balik dan rekod persetujuan dipadam."))
+	return true
+}
+
+func handleDataSaya(ctx *CommandContext) {
+	if sent, err := sendUserDataExport(ctx.Bot, ctx.ChatID, ctx.UserID); err == nil {
+		ctx.Track(sent.MessageID)
+	}
+}
+
+// sendUserDataExport menghantar semua data user sebagai fail JSON (atau mesej ralat storan)
+func sendUserDataExport(bot *tgbotapi.BotAPI, chatID, userID int64) (tgbotapi.Message, error) {
+	data, err := ExportUserData(userID)
+	if err != nil {
+		log.Printf("Ralat eksport data user %d: %v", userID, err)
+		return bot.Send(tgbotapi.NewMessage(chatID, "❌ Ralat teknikal (storan), sila cuba lagi."))
+	}
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("data_saya_%d.json", userID),
+		Bytes: data,
+	})
+	doc.Caption = "📄 Semua data yang kami simpan tentang anda."
+	return bot.Send(doc)
+}
+
+func handleBan(ctx *CommandContext) {
+	targetID, rest, err := commandTarget(ctx)
+	if err != nil {
+		replyTargetError(ctx, err)
+		return
+	}
+
+	// Argumen pilihan: tempoh (contoh 24h, 7d) diikuti sebab
+	var duration time.Duration
+	var durationText string
+	if len(rest) > 0 {
+		if d, ok := parseBanDuration(rest[0]); ok {
+			duration, durationText = d, rest[0]
+			rest = rest[1:]
+		}
+	}
+	reason := "Sekatan Manual oleh Admin"
+	if len(rest) > 0 {
+		reason = strings.Join(rest, " ")
+	}
+	if err := checkBanTarget(ctx.UserID, targetID); err != nil {
+		ctx.Reply("❌ " + err.Error())
+		return
+	}
+
+	if err := ManualBan(ctx.Bot, ctx.UserID, targetID, reason, StaffName(ctx.UserID, ctx.Username), duration); err != nil {
+		ctx.Reply(fmt.Sprintf("❌ Gagal menyekat user: %v", err))
+		return
+	}
+	// Beri maklum balas kepada Admin
+	if duration > 0 {
+		ctx.Reply(fmt.Sprintf("✅ User %d telah disekat selama %s (sebab: %s) dan notis telah dihantar.", targetID, durationText, reason))
+		return
+	}
+	ctx.Reply(fmt.Sprintf("✅ User %d telah berjaya disekat dan notis telah dihantar.", targetID))
+}
+
+// checkBanTarget menolak sekatan ke atas diri sendiri atau staf yang dikecualikan dari sekatan
+func checkBanTarget(adminID, targetID int64) error {
+	if targetID == adminID {
+		return fmt.Errorf("anda tidak boleh menyekat diri sendiri")
+	}
+	if role := RoleOf(targetID); role.Can(PermBypassChecks) {
+		return fmt.Errorf("user %d ialah staf (%s) dan tidak boleh disekat", targetID, role)
+	}
+	return nil
+}
+
+// ManualBan menghantar notis sekatan rasmi kepada user dan menyimpan rekod sekatan.
+// duration 0 bermaksud sekatan kekal.
+func ManualBan(bot *tgbotapi.BotAPI, adminID int64, targetID int64, reason string, byAdmin string, duration time.Duration) error {
+	status := "*Disekat (KEKAL)*"
+	if duration > 0 {
+		status = fmt.Sprintf("*Disekat sementara* sehingga %s", time.Now().Add(duration).Format("2006-01-02 15:04"))
+	}
+
+	// Hantar Mesej Rasmi kepada User tersebut
+	notisManual := fmt.Sprintf(
+		"🚫 *NOTIS SEKATAN RASMI*\n\n"+
+			"Akaun anda telah *DISEKAT SECARA MANUAL* oleh Admin atas pelanggaran syarat.\n\n"+
+			"Status: %s\n\n"+
+			"Jika ini adalah kesilapan atau anda ingin buka sekatan perlu kemukakan rayuan dan bayaran denda kesalahan.\n"+
+			"👉 Taip `/rayuan <penjelasan anda>` terus di dalam bot ini (sekali sahaja).\n\n"+
+			"_ID Rujukan: %d_", status, targetID)
+
+	msgToUser := tgbotapi.NewMessage(targetID, notisManual)
+	msgToUser.ParseMode = tgbotapi.ModeMarkdown
+	if _, err := bot.Send(msgToUser); err != nil {
+		// User mungkin sudah block bot atau tidak aktif
+		log.Printf("Gagal hantar notis ban ke user %d: %v", targetID, err)
+	}
+
+	// Jalankan fungsi BanUser untuk simpan ke storan
+	if err := BanUser(targetID, reason, byAdmin, duration); err != nil {
+		return err
+	}
+	countStat(statBanManual)
+	detail := "kekal"
+	if duration > 0 {
+		detail = "tamat " + time.Now().Add(duration).Format("2006-01-02 15:04")
+	}
+	logAudit(AuditRecord{Actor: byAdmin, ActorID: adminID, Action: auditBan, Target: targetID, Reason: reason, Detail: detail})
+	return nil
+}
+
+func handleUnban(ctx *CommandContext) {
+	targetID, _, err := commandTarget(ctx)
+	if err != nil {
+		replyTargetError(ctx, err)
+		return
+	}
+
+	notified, err := UnbanUser(ctx.Bot, ctx.UserID, targetID)
+	ctx.Reply(unbanResultText(targetID, notified, err))
+}
+
+// unbanResultText ialah maklum balas kepada Admin selepas UnbanUser
+func unbanResultText(targetID int64, notified bool, err error) string {
+	switch {
+	case errors.Is(err, errNotBanned):
+		return fmt.Sprintf("ℹ️ User %d tiada dalam senarai sekatan.", targetID)
+	case err != nil:
+		return fmt.Sprintf("❌ Gagal membuang sekatan user %d: %v", targetID, err)
+	case !notified:
+		return fmt.Sprintf("✅ Sekatan user %d telah dibuang, tetapi notis tidak dapat dihantar (user mungkin telah block bot).", targetID)
+	}
+	return fmt.Sprintf("✅ Sekatan user %d telah dibuang dan notis telah dihantar.", targetID)
+}
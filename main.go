@@ -2,13 +2,15 @@ package main
 
 import (
     "encoding/json"
+    "expvar"
     "fmt"
     "log"
     "net/http"
     "os"
-    "strconv"
+    "os/signal"
     "strings"
     "sync"
+    "syscall"
 
     tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -106,7 +108,6 @@ var linksInlineKeyboard = tgbotapi.NewInlineKeyboardMarkup(
 // ================================================
 func isAllowedText(text string) bool {
     allowed := map[string]bool{
-        "/start":                    true,
         "🔙 Kembali Menu Utama":     true,
         "📚 Panduan Kripto":         true,
         "🔗 Pautan & 🆘 Bantuan":    true,
@@ -118,7 +119,8 @@ func isAllowedText(text string) bool {
 
 // --- FUNGSI-FUNGSI SEDIA ADA (ASAL) ---
 // loadGuides, addMessageID, sendDetailedGuide, sendInfographicGuide
-// CheckSpam, ExecuteAutoBan, IsBanned, HasAgreed, IsAdmin,
+// CheckSpam, ExecuteAutoBan, IsBanned, HasAgreed, HasPermission,
+// BannedWithPolicy, AgreedWithPolicy,
 // SaveAgreementToGithub, BanUser, BuildTermsUI
 // (semua fungsi ni ADA dalam fail asal, jangan padam!)
 
@@ -273,8 +275,74 @@ func sendInfographicGuide(bot *tgbotapi.BotAPI, chatID int64, guide InfographicG
     }
 }
 
+// alertAdmin menghantar amaran kepada Admin; ditetapkan selepas bot dimulakan
+var alertAdmin = func(text string) {
+    log.Printf("📣 (Admin belum sedia) %s", text)
+}
+
+// sendTermsGate memaparkan terma (berserta ringkasan perubahan jika perlu) dan butang Setuju/Tidak Setuju
+func sendTermsGate(bot *tgbotapi.BotAPI, chatID int64, userID int64, messageIDs *map[int64][]int, mu *sync.Mutex) {
+    parts, err := BuildTermsUI()
+    if err != nil {
+        bot.Send(tgbotapi.NewMessage(chatID, "❌ Ralat memuatkan terma."))
+        return
+    }
+
+    // User lama dengan persetujuan versi terdahulu -> tunjuk ringkasan perubahan
+    if accepted := ReconsentVersion(userID); accepted != "" {
+        changes := tgbotapi.NewMessage(chatID, BuildTermsChangesUI(accepted))
+        changes.ParseMode = config.TermsParseMode
+        sentChanges, _ := bot.Send(changes)
+        addMessageID(messageIDs, mu, chatID, sentChanges.MessageID)
+    }
+
+    // Terma panjang dihantar dalam beberapa mesej; butang pada mesej terakhir
+    for i, txt := range parts {
+        msg := tgbotapi.NewMessage(chatID, txt)
+        msg.ParseMode = config.TermsParseMode
+        if i == len(parts)-1 {
+            msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+                tgbotapi.NewInlineKeyboardRow(
+                    tgbotapi.NewInlineKeyboardButtonData("Setuju ✅", "setuju_tnc"),
+                    tgbotapi.NewInlineKeyboardButtonData("Tidak Setuju ❌", "tolak_tnc"),
+                ),
+            )
+        }
+        sentMsg, _ := bot.Send(msg)
+        addMessageID(messageIDs, mu, chatID, sentMsg.MessageID)
+    }
+}
+
+// sendMainMenu memaparkan menu utama kepada user sah, atau pintu terma kepada user baru
+func sendMainMenu(bot *tgbotapi.BotAPI, chatID int64, userID int64, isAllowed bool, messageIDs *map[int64][]int, mu *sync.Mutex) {
+    if !isAllowed {
+        // User Baru -> Tunjuk Terms UI
+        sendTermsGate(bot, chatID, userID, messageIDs, mu)
+        return
+    }
+
+    // User Sah
+    audio := tgbotapi.NewAudio(chatID, tgbotapi.FileURL(WELCOME_JINGLE_URL))
+    audio.Caption = "🎶 Selamat datang ke Cryptorian!"
+    audio.ParseMode = tgbotapi.ModeMarkdown
+    sentAudio, _ := bot.Send(audio)
+    addMessageID(messageIDs, mu, chatID, sentAudio.MessageID)
+
+    text := "*👋 Selamat Datang ke 🤖 Cryptorian-Telebot!*"
+    msg := tgbotapi.NewMessage(chatID, text)
+    msg.ReplyMarkup = mainMenuReplyKeyboard
+    msg.ParseMode = tgbotapi.ModeMarkdown
+    sentMsg, _ := bot.Send(msg)
+    addMessageID(messageIDs, mu, chatID, sentMsg.MessageID)
+}
+
 // --- FUNGSI UTAMA (MAIN) ---
 func main() {
+    // Subcommand: tandatangan terms.json (tidak memerlukan token bot)
+    if len(os.Args) > 1 && os.Args[1] == "sign-terms" {
+        os.Exit(runSignTerms(os.Args[2:]))
+    }
+
     botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
     if botToken == "" {
         log.Fatal("❌ TELEGRAM_BOT_TOKEN mesti ditetapkan")
@@ -286,25 +354,102 @@ func main() {
     }
     log.Printf("✅ Bot Hibrid UI dimulakan: @%s", bot.Self.UserName)
 
+    alertAdmin = func(text string) {
+        notifyStaff(bot, PermSystemAlerts, text)
+    }
+
     if err := loadGuides(); err != nil {
         log.Fatalf("❌ %v", err)
     }
 
+    // --- SETUP STORAN (github / fs / sqlite) ---
+    config = loadConfig()
+    store, err = newStore(config)
+    if err != nil {
+        log.Fatalf("❌ Gagal inisialisasi storan: %v", err)
+    }
+    log.Printf("🗄️ Backend storan: %s", config.StorageBackend)
+
+    // Tulisan yang gagal secara kekal dilaporkan kepada Admin
+    storeQueue.onFailure = func(op journalOp, err error) {
+        alert := fmt.Sprintf(
+            "⚠️ *TULISAN STORAN GAGAL*\n\n"+
+            "Operasi: `%s`\n"+
+            "🆔 User ID: `%d`\n"+
+            "⏰ Dijurnal: %s\n\n"+
+            "Ralat: %v", op.Kind, op.userID(), op.CreatedAt.Format("2006-01-02 15:04:05"), err)
+        alertAdmin(alert)
+    }
+    storeQueue.start()
+
+    // Indeks username -> ID (untuk /ban @username) diisi dari rekod persetujuan
+    go loadUsernameIndex()
+
+    // Muat terma sekali ke memori, kemudian segarkan di latar belakang
+    loadTerms()
+    startTermsRefresh(config.TermsRefreshInterval)
+
+    // Kaunter statistik (/stats) dimuat dari STATE_DIR dan disimpan berkala
+    loadStats()
+    startStatsFlush(config.StatsFlushInterval)
+
+    // --- SHUTDOWN: simpan kaunter sebelum keluar (SIGTERM semasa redeploy) ---
+    shutdown := make(chan os.Signal, 1)
+    signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+    go func() {
+        sig := <-shutdown
+        log.Printf("🛑 Isyarat %v diterima, menyimpan statistik sebelum keluar...", sig)
+        bot.StopReceivingUpdates()
+        if err := flushStats(); err != nil {
+            log.Printf("⚠️ Gagal simpan statistik: %v", err)
+        }
+        os.Exit(0)
+    }()
+
+    // Had anti-spam (token bucket) dari konfigurasi
+    initAntiSpam(config)
+    loadStrikeHistory()
+    startSpamJanitor(config.SpamJanitorInterval)
+
+    // Rekod konfigurasi dalam log audit jika berubah sejak startup terakhir
+    auditConfigOnStartup(config)
+
+    // Tarik balik sekatan sementara yang telah tamat
+    startBanExpiry(bot, config.BanExpiryInterval)
+
     // --- SETUP SERVER HTTP UNTUK KOYEB ---
     go func() {
         port := os.Getenv("PORT")
         if port == "" {
             port = "8080"
         }
-        http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+        // Mux sendiri: metrik expvar (didaftarkan pada DefaultServeMux) tidak didedahkan di port awam
+        mux := http.NewServeMux()
+        mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
             fmt.Fprintf(w, "✅ Cryptorian Bot is Running Live!")
         })
         log.Printf("🚀 Health Check Server bermula di port: %s", port)
-        if err := http.ListenAndServe(":"+port, nil); err != nil {
+        if err := http.ListenAndServe(":"+port, mux); err != nil {
             log.Printf("⚠️ Gagal memulakan HTTP server: %v", err)
         }
     }()
 
+    // --- SERVER METRIK (expvar /debug/vars, localhost secara lalai) ---
+    if config.MetricsAddr != "off" {
+        go func() {
+            mux := http.NewServeMux()
+            mux.Handle("/debug/vars", expvar.Handler())
+            log.Printf("📈 Metrik expvar di http://%s/debug/vars", config.MetricsAddr)
+            if err := http.ListenAndServe(config.MetricsAddr, mux); err != nil {
+                log.Printf("⚠️ Gagal memulakan server metrik: %v", err)
+            }
+        }()
+    }
+
+    // --- SETUP ARAHAN ---
+    commands := newCommandRouter()
+    registerCommands(commands)
+
     // --- SETUP UPDATE CHANNEL ---
     u := tgbotapi.NewUpdate(0)
     u.Timeout = 60
@@ -336,13 +481,33 @@ func main() {
         }
 
        // ===== ANTI-SPAM =====
-       if CheckSpam(userID) {
-        ExecuteAutoBan(bot, chatID, userID, username)
+       // User dalam cooldown senyap diabaikan sepenuhnya
+       if InSpamCooldown(userID) {
         continue
        }
+       if kind := actionKindOf(update); CheckSpam(userID, kind) {
+        // User yang sudah disekat tidak dinaikkan tangga hukuman lagi
+        if !BannedWithPolicy(userID) {
+            ApplySpamPenalty(bot, chatID, userID, username, kind)
+        }
+        continue
+       }
+
+        // ===== JEJAK AKTIVITI (/whois) & INDEKS USERNAME =====
+        observeUpdate(update)
+        switch {
+        case update.CallbackQuery != nil:
+            recordActivity(userID, username, "butang: "+update.CallbackQuery.Data)
+        case update.Message.Voice != nil:
+            recordActivity(userID, username, "voice message")
+        default:
+            recordActivity(userID, username, update.Message.Text)
+        }
 
         // ===== BLACKLIST =====
-        if IsBanned(userID) {
+        if BannedWithPolicy(userID) {
+            // User disekat hanya boleh menghantar /rayuan, /data_saya & /tarik_persetujuan
+            handleBannedUpdate(bot, update, userID, username)
             continue
         }
 
@@ -352,11 +517,14 @@ func main() {
 
             // A. Setuju T&C
             if callback.Data == "setuju_tnc" {
-                err := SaveAgreementToGithub(userID, username)
+                created, err := SaveAgreementToGithub(userID, username)
                 responseText := "✅ Persetujuan direkodkan! Sila taip /start untuk mula."
                 if err != nil {
-                    log.Printf("Ralat Github: %v", err)
-                    responseText = "❌ Ralat teknikal (Github), sila cuba lagi."
+                    log.Printf("Ralat storan: %v", err)
+                    responseText = "❌ Ralat teknikal (storan), sila cuba lagi."
+                } else if created {
+                    // Kira user baharu sahaja, bukan tekanan "Setuju" berulang
+                    countStat(statAgreed)
                 }
                 bot.Send(tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, responseText))
                 bot.Request(tgbotapi.NewCallback(callback.ID, ""))
@@ -374,18 +542,49 @@ func main() {
             }
 
             
-            // C. Menu Navigasi
-if HasAgreed(userID) || IsAdmin(userID) {
+            // C. Tarik Balik Persetujuan (PDPA)
+            if isWithdrawCallback(callback.Data) {
+                if handleWithdrawCallback(bot, callback) {
+                    // Kembali ke pintu terma
+                    sendTermsGate(bot, chatID, userID, &messageIDsToDelete, &mu)
+                }
+                continue
+            }
+
+            // D. Keputusan Rayuan (Admin)
+            if isAppealCallback(callback.Data) {
+                handleAppealCallback(bot, callback)
+                continue
+            }
+
+            // E. Butang pintas /whois (Admin)
+            if isWhoisCallback(callback.Data) {
+                handleWhoisCallback(bot, callback)
+                continue
+            }
+
+            // F. Siaran (Admin)
+            if isBroadcastCallback(callback.Data) {
+                handleBroadcastCallback(bot, callback)
+                continue
+            }
+
+            // G. Menu Navigasi
+if HasPermission(userID, PermBypassChecks) || AgreedWithPolicy(userID) {
     switch callback.Data {
     case "close_menu":
         bot.Request(tgbotapi.NewDeleteMessage(chatID, callback.Message.MessageID))
     case "get_guide_claim":
+        countGuide(userID, statGuideClaim)
         sendDetailedGuide(bot, chatID, worldcoinGuide, &messageIDsToDelete, &mu)
     case "get_guide_wallet":
+        countGuide(userID, statGuideWallet)
         sendDetailedGuide(bot, chatID, hataGuide, &messageIDsToDelete, &mu)
     case "get_guide_cashout":
+        countGuide(userID, statGuideCashout)
         sendDetailedGuide(bot, chatID, cashoutGuide, &messageIDsToDelete, &mu)
     case "get_guide_website":  // ✅ TAMBAH SINI!
+        countGuide(userID, statGuideWebsite)
         // Hantar link website
         msg := tgbotapi.NewMessage(chatID, 
             "🌐 *Website Cryptorian*\n\n"+
@@ -416,8 +615,25 @@ if HasAgreed(userID) || IsAdmin(userID) {
         }
         addMessageID(&messageIDsToDelete, &mu, chatID, update.Message.MessageID)
 
-        // ===== TOLAK MESEJ TEKS BIASA YANG TAK DIKENALI =====
-        if !isAllowedText(update.Message.Text) && update.Message.Text != "" {
+        role := RoleOf(userID)
+
+        // ===== ARAHAN (/start, /help, /ban, ...) =====
+        if update.Message.IsCommand() {
+            commands.Dispatch(&CommandContext{
+                Bot:        bot,
+                Message:    update.Message,
+                UserID:     userID,
+                ChatID:     chatID,
+                Username:   username,
+                Role:       role,
+                messageIDs: &messageIDsToDelete,
+                mu:         &mu,
+            })
+            continue
+        }
+
+        // ===== TOLAK MESEJ TEKS BIASA YANG TAK DIKENALI (bukan pasukan moderasi) =====
+        if !role.Can(PermBypassChecks) && !isAllowedText(update.Message.Text) && update.Message.Text != "" {
             reply := "❌ *Mesej teks tidak diterima.*\n\nSila gunakan butang menu yang tersedia."
             msg := tgbotapi.NewMessage(chatID, reply)
             msg.ParseMode = tgbotapi.ModeMarkdown
@@ -425,60 +641,11 @@ if HasAgreed(userID) || IsAdmin(userID) {
             continue
         }
 
-        // 5. KUASA ADMIN (/BAN)
-        if strings.HasPrefix(update.Message.Text, "/ban") {
-            // Semak jika user adalah admin
-            if !IsAdmin(userID) {
-                continue
-            }
-            
-            // Ambil ID dari mesej (Contoh: /ban 12345)
-            args := strings.Split(update.Message.Text, " ")
-            if len(args) < 2 { 
-                bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Format salah: /ban [user_id]"))
-                continue
-            }
-            
-            targetID, err := strconv.ParseInt(args[1], 10, 64)
-            if err != nil {
-                bot.Send(tgbotapi.NewMessage(chatID, "❌ ID tidak sah. Sila masukkan nombor ID yang betul."))
-                continue
-            }
-
-            // Hantar Mesej Rasmi kepada User tersebut
-            notisManual := fmt.Sprintf(
-                "🚫 *NOTIS SEKATAN RASMI*\n\n"+
-                "Akaun anda telah *DISEKAT SECARA MANUAL* oleh Admin atas pelanggaran syarat.\n\n"+
-                "Status: *Disekat (KEKAL)*\n\n"+
-                "Jika ini adalah kesilapan atau anda ingin buka sekatan perlu kemukakan rayuan dan bayaran denda kesalahan. Sila hubungi:\n"+
-                "👉[Hubungi Admin](https://t.me/johansetia)\n\n"+
-                "_ID Rujukan: %d_", targetID)
-
-            msgToUser := tgbotapi.NewMessage(targetID, notisManual)
-            msgToUser.ParseMode = tgbotapi.ModeMarkdown
-            _, err = bot.Send(msgToUser)
-            
-            if err != nil {
-                // User mungkin sudah block bot atau tidak aktif
-                log.Printf("Gagal hantar notis ban ke user %d: %v", targetID, err)
-            }
-
-            // Jalankan fungsi BanUser untuk simpan ke GitHub
-            err = BanUser(targetID, "Sekatan Manual oleh Admin")
-            if err != nil {
-                bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Gagal menyekat user: %v", err)))
-            } else {
-                // Beri maklum balas kepada Admin
-                bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ User %d telah berjaya disekat dan notis telah dihantar.", targetID)))
-            }
-            continue
-        }
-
         // 6. GATEKEEPER
-        isAllowed := IsAdmin(userID) || HasAgreed(userID)
+        isAllowed := role.Can(PermBypassChecks) || AgreedWithPolicy(userID)
 
-        if !isAllowed && update.Message.Command() != "start" {
-            msg := tgbotapi.NewMessage(chatID, "⚠️ Akses dihadkan. Sila taip /start.")
+        if !isAllowed {
+            msg := tgbotapi.NewMessage(chatID, accessDeniedText(userID))
             sentMsg, _ := bot.Send(msg)
             addMessageID(&messageIDsToDelete, &mu, chatID, sentMsg.MessageID)
             continue
@@ -486,39 +653,8 @@ if HasAgreed(userID) || IsAdmin(userID) {
 
         // 7. MENU UTAMA
         switch update.Message.Text {
-        case "/start", "🔙 Kembali Menu Utama":
-            if isAllowed {
-                // User Sah
-                audio := tgbotapi.NewAudio(chatID, tgbotapi.FileURL(WELCOME_JINGLE_URL))
-                audio.Caption = "🎶 Selamat datang ke Cryptorian!"
-                audio.ParseMode = tgbotapi.ModeMarkdown
-                sentAudio, _ := bot.Send(audio)
-                addMessageID(&messageIDsToDelete, &mu, chatID, sentAudio.MessageID)
-
-                text := "*👋 Selamat Datang ke 🤖 Cryptorian-Telebot!*"
-                msg := tgbotapi.NewMessage(chatID, text)
-                msg.ReplyMarkup = mainMenuReplyKeyboard
-                msg.ParseMode = tgbotapi.ModeMarkdown
-                sentMsg, _ := bot.Send(msg)
-                addMessageID(&messageIDsToDelete, &mu, chatID, sentMsg.MessageID)
-            } else {
-                // User Baru -> Tunjuk Terms UI
-                txt, err := BuildTermsUI()
-                if err != nil {
-                    bot.Send(tgbotapi.NewMessage(chatID, "❌ Ralat memuatkan terma."))
-                    continue
-                }
-                msg := tgbotapi.NewMessage(chatID, txt)
-                msg.ParseMode = tgbotapi.ModeMarkdown
-                msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-                    tgbotapi.NewInlineKeyboardRow(
-                        tgbotapi.NewInlineKeyboardButtonData("Setuju ✅", "setuju_tnc"),
-                        tgbotapi.NewInlineKeyboardButtonData("Tidak Setuju ❌", "tolak_tnc"),
-                    ),
-                )
-                sentMsg, _ := bot.Send(msg)
-                addMessageID(&messageIDsToDelete, &mu, chatID, sentMsg.MessageID)
-            }
+        case "🔙 Kembali Menu Utama":
+            sendMainMenu(bot, chatID, userID, isAllowed, &messageIDsToDelete, &mu)
 
         case "📚 Panduan Kripto":
             if isAllowed {
@@ -542,11 +678,13 @@ if HasAgreed(userID) || IsAdmin(userID) {
 
         case "📊 Infografik":
             if isAllowed {
+                countGuide(userID, statInfographic)
                 sendInfographicGuide(bot, chatID, infographicGuide, &messageIDsToDelete, &mu)
             }
 
         case "♻️ Reset Mesej":
             if isAllowed {
+                countStat(statReset)
                 mu.Lock()
                 if ids, exists := messageIDsToDelete[chatID]; exists {
                     for _, id := range ids {
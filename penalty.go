@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Jenis langkah dalam tangga hukuman anti-spam
+const (
+	penaltyWarn     = "warn"
+	penaltyCooldown = "cooldown"
+	penaltyBan      = "ban"
+)
+
+// penaltyStep ialah satu langkah tangga hukuman. Duration: tempoh cooldown atau
+// sekatan sementara (0 bagi "ban" bermaksud sekatan kekal).
+type penaltyStep struct {
+	Kind     string
+	Duration time.Duration
+}
+
+func (s penaltyStep) String() string {
+	if s.Duration > 0 {
+		return fmt.Sprintf("%s:%s", s.Kind, formatPenaltyDuration(s.Duration))
+	}
+	return s.Kind
+}
+
+// describe ialah keterangan langkah untuk notis kepada user
+func (s penaltyStep) describe() string {
+	switch {
+	case s.Kind == penaltyWarn:
+		return "amaran"
+	case s.Kind == penaltyCooldown:
+		return fmt.Sprintf("bot tidak melayan anda selama %s", formatPenaltyDuration(s.Duration))
+	case s.Duration > 0:
+		return fmt.Sprintf("sekatan sementara %s", formatPenaltyDuration(s.Duration))
+	}
+	return "sekatan kekal (perlu rayuan & bayaran denda)"
+}
+
+// formatPenaltyDuration memaparkan tempoh dalam bentuk yang sama dengan input ("7d", "10m")
+func formatPenaltyDuration(d time.Duration) string {
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	s := strings.TrimSuffix(d.String(), "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// defaultPenaltyLadder: amaran → cooldown 10 minit → sekatan 24 jam → sekatan kekal
+const defaultPenaltyLadder = "warn,cooldown:10m,ban:24h,ban"
+
+// parsePenaltyLadder membaca tangga seperti "warn,cooldown:10m,ban:24h,ban"
+func parsePenaltyLadder(s string) ([]penaltyStep, error) {
+	var ladder []penaltyStep
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		kind, durStr, hasDur := strings.Cut(part, ":")
+		step := penaltyStep{Kind: kind}
+		if hasDur {
+			d, ok := parseBanDuration(durStr)
+			if !ok {
+				return nil, fmt.Errorf("tempoh tidak sah dalam %q", part)
+			}
+			step.Duration = d
+		}
+		switch {
+		case kind == penaltyWarn && !hasDur, kind == penaltyCooldown && hasDur, kind == penaltyBan:
+		default:
+			return nil, fmt.Errorf("langkah tidak sah: %q", part)
+		}
+		ladder = append(ladder, step)
+	}
+	if len(ladder) == 0 {
+		return nil, fmt.Errorf("tangga hukuman kosong")
+	}
+	return ladder, nil
+}
+
+// spamStrikes ialah sejarah kesalahan spam setiap user (dalam tempoh simpanan, disalin
+// ke storan) dan tamat cooldown senyap yang sedang berkuat kuasa
+var spamStrikes = struct {
+	sync.Mutex
+	records  map[int64]*StrikeRecord
+	cooldown map[int64]time.Time
+}{records: make(map[int64]*StrikeRecord), cooldown: make(map[int64]time.Time)}
+
+// strikeRetention ialah tempoh sejarah kesalahan disimpan (sekurang-kurangnya tempoh lookback)
+func strikeRetention() time.Duration {
+	return max(config.SpamStrikeRetention, config.SpamLookback)
+}
+
+// strikeDebounce ialah masa untuk token anti-spam user penuh semula. Letusan yang sama
+// (token masih habis) tidak dikira sebagai kesalahan baharu dalam tempoh ini.
+func strikeDebounce(userID int64) time.Duration {
+	limiter, _ := spamLimiterFor(RoleOf(userID))
+	return limiter.Limit().FullAfter()
+}
+
+// Countable memulangkan bilangan kesalahan yang dikira untuk tangga hukuman pada masa now
+func (r StrikeRecord) Countable(now time.Time, lookback time.Duration) int {
+	n := 0
+	for _, ev := range r.Events {
+		if now.Sub(ev.At) < lookback && (r.ClearedAt == nil || ev.At.After(*r.ClearedAt)) {
+			n++
+		}
+	}
+	return n
+}
+
+// copy memulangkan salinan rekod yang selamat digunakan di luar kunci
+func (r *StrikeRecord) copy() StrikeRecord {
+	cp := *r
+	cp.Events = append([]StrikeEvent(nil), r.Events...)
+	return cp
+}
+
+// recordStrike merekod satu kesalahan dan memulangkan bilangan kesalahan dalam tempoh
+// lookback. ok=false jika kesalahan ini sebahagian daripada letusan yang telah dihukum.
+func recordStrike(userID int64, ev StrikeEvent, lookback time.Duration) (count int, ok bool) {
+	debounce := strikeDebounce(userID)
+	spamStrikes.Lock()
+	defer spamStrikes.Unlock()
+	rec := spamStrikes.records[userID]
+	if rec == nil {
+		rec = &StrikeRecord{UserID: userID}
+		spamStrikes.records[userID] = rec
+	}
+	if n := len(rec.Events); n > 0 && ev.At.Sub(rec.Events[n-1].At) < debounce {
+		return rec.Countable(ev.At, lookback), false
+	}
+	rec.Events = append(rec.Events, ev)
+	return rec.Countable(ev.At, lookback), true
+}
+
+// persistStrike menanda hukuman pada kesalahan terakhir user dan menyimpan sejarahnya
+func persistStrike(userID int64, penalty string) {
+	spamStrikes.Lock()
+	rec := spamStrikes.records[userID]
+	if rec == nil || len(rec.Events) == 0 {
+		spamStrikes.Unlock()
+		return
+	}
+	rec.Events[len(rec.Events)-1].Penalty = penalty
+	cp := rec.copy()
+	spamStrikes.Unlock()
+	saveStrikes(cp)
+}
+
+// saveStrikes menyimpan (atau memadam jika kosong) sejarah kesalahan dalam storan
+func saveStrikes(rec StrikeRecord) {
+	if store == nil {
+		return
+	}
+	var err error
+	if len(rec.Events) == 0 {
+		err = store.DeleteStrikes(rec.UserID)
+	} else {
+		err = store.SaveStrikes(rec)
+	}
+	if err != nil {
+		log.Printf("⚠️ Gagal simpan sejarah kesalahan spam user %d: %v", rec.UserID, err)
+	}
+}
+
+// trimStrikes membuang kesalahan yang lebih lama daripada retention (dipanggil dengan kunci)
+func trimStrikes(rec *StrikeRecord, now time.Time, retention time.Duration) bool {
+	kept := rec.Events[:0]
+	for _, ev := range rec.Events {
+		if now.Sub(ev.At) < retention {
+			kept = append(kept, ev)
+		}
+	}
+	changed := len(kept) != len(rec.Events)
+	rec.Events = kept
+	return changed
+}
+
+// loadStrikeHistory memuatkan sejarah kesalahan dari storan semasa startup dan
+// membuang rekod yang telah melepasi tempoh simpanan
+func loadStrikeHistory() {
+	recs, err := store.ListStrikes()
+	if err != nil {
+		log.Printf("⚠️ Gagal muat sejarah kesalahan spam dari storan: %v", err)
+		return
+	}
+	now := time.Now()
+	var stale []StrikeRecord
+	spamStrikes.Lock()
+	for i := range recs {
+		rec := recs[i]
+		if trimStrikes(&rec, now, strikeRetention()) {
+			stale = append(stale, rec.copy())
+		}
+		if len(rec.Events) > 0 {
+			spamStrikes.records[rec.UserID] = &rec
+		}
+	}
+	loaded := len(spamStrikes.records)
+	spamStrikes.Unlock()
+
+	for _, rec := range stale {
+		saveStrikes(rec)
+	}
+	log.Printf("🛡️ Sejarah kesalahan spam: %d user dimuatkan (%d dipangkas)", loaded, len(stale))
+}
+
+// StrikeHistory memulangkan salinan sejarah kesalahan seorang user
+func StrikeHistory(userID int64) (StrikeRecord, bool) {
+	spamStrikes.Lock()
+	defer spamStrikes.Unlock()
+	rec, ok := spamStrikes.records[userID]
+	if !ok {
+		return StrikeRecord{}, false
+	}
+	return rec.copy(), true
+}
+
+// InSpamCooldown menyemak sama ada user sedang dalam cooldown senyap
+func InSpamCooldown(userID int64) bool {
+	spamStrikes.Lock()
+	defer spamStrikes.Unlock()
+	until, ok := spamStrikes.cooldown[userID]
+	if !ok {
+		return false
+	}
+	if time.Now().Before(until) {
+		return true
+	}
+	delete(spamStrikes.cooldown, userID)
+	return false
+}
+
+// pruneSpamStrikes membuang kesalahan di luar tempoh simpanan (dari memori dan storan)
+// dan cooldown yang tamat. Memulangkan bilangan user yang sejarahnya dipangkas.
+func pruneSpamStrikes(now time.Time, retention time.Duration) int {
+	var changed []StrikeRecord
+	spamStrikes.Lock()
+	for id, rec := range spamStrikes.records {
+		if trimStrikes(rec, now, retention) {
+			changed = append(changed, rec.copy())
+		}
+		if len(rec.Events) == 0 {
+			delete(spamStrikes.records, id)
+		}
+	}
+	for id, until := range spamStrikes.cooldown {
+		if !now.Before(until) {
+			delete(spamStrikes.cooldown, id)
+		}
+	}
+	spamStrikes.Unlock()
+
+	for _, rec := range changed {
+		saveStrikes(rec)
+	}
+	return len(changed)
+}
+
+// clearSpamStrikes memaafkan kesalahan user dan membuang cooldown (contoh: selepas unban).
+// Sejarah disimpan untuk semakan Admin tetapi tidak lagi dikira untuk tangga hukuman.
+func clearSpamStrikes(userID int64) {
+	spamStrikes.Lock()
+	delete(spamStrikes.cooldown, userID)
+	rec, ok := spamStrikes.records[userID]
+	if !ok {
+		spamStrikes.Unlock()
+		return
+	}
+	now := time.Now()
+	rec.ClearedAt = &now
+	cp := rec.copy()
+	spamStrikes.Unlock()
+	saveStrikes(cp)
+}
+
+// ApplySpamPenalty menaikkan tangga hukuman bagi user yang mencetuskan anti-spam:
+// amaran, cooldown senyap, sekatan sementara, kemudian sekatan kekal
+func ApplySpamPenalty(bot *tgbotapi.BotAPI, chatID int64, userID int64, username string, kind ActionKind) {
+	// Pasukan moderasi hanya dihadkan kadar (jika diaktifkan), tanpa hukuman
+	if HasPermission(userID, PermBypassChecks) {
+		return
+	}
+	now := time.Now()
+	strike, ok := recordStrike(userID, StrikeEvent{At: now, Action: kind, ChatID: chatID}, config.SpamLookback)
+	if !ok {
+		return // Letusan yang sama: abaikan secara senyap
+	}
+	ladder := config.SpamLadder
+	step := ladder[min(strike, len(ladder))-1]
+	persistStrike(userID, step.String())
+	log.Printf("🛡️ Spam user %d (@%s): kesalahan ke-%d dalam %s → %s",
+		userID, username, strike, formatPenaltyDuration(config.SpamLookback), step)
+
+	next := ""
+	if strike < len(ladder) {
+		next = fmt.Sprintf("\n\nJika berulang dalam tempoh %s: *%s*.",
+			formatPenaltyDuration(config.SpamLookback), ladder[strike].describe())
+	}
+
+	var notice string
+	switch step.Kind {
+	case penaltyWarn:
+		notice = "⚠️ *AMARAN SPAM*\n\n" +
+			"Sistem mengesan tekanan butang/mesej yang terlalu laju dari akaun anda.\n" +
+			"Sila tunggu sebentar sebelum menekan semula." + next
+	case penaltyCooldown:
+		spamStrikes.Lock()
+		spamStrikes.cooldown[userID] = now.Add(step.Duration)
+		spamStrikes.Unlock()
+		notice = fmt.Sprintf("⏳ *COOLDOWN SPAM*\n\n"+
+			"Aktiviti spam berulang dikesan. Bot tidak akan melayan sebarang mesej atau butang "+
+			"dari akaun anda selama *%s* (sehingga %s).", formatPenaltyDuration(step.Duration),
+			now.Add(step.Duration).Format("2006-01-02 15:04")) + next
+	default:
+		ExecuteAutoBan(bot, chatID, userID, username, step.Duration)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, notice)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("Gagal hantar notis spam ke user %d: %v", userID, err)
+	}
+}
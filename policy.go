@@ -0,0 +1,152 @@
+package main
+
+import (
+	"container/list"
+	"expvar"
+	"log"
+	"sync"
+	"time"
+)
+
+// Polisi apabila carian storan gagal (contoh: GitHub tidak dapat dihubungi)
+const (
+	policyFailClosed = "closed"     // anggap user disekat / belum setuju
+	policyFailOpen   = "open"       // anggap user tidak disekat / sudah setuju
+	policyLastKnown  = "last_known" // guna nilai terakhir yang berjaya dibaca
+)
+
+// degradedDecisions mengira setiap keputusan yang dibuat tanpa jawapan sebenar dari storan.
+// Didedahkan di /debug/vars oleh pelayan metrik (METRICS_ADDR), bukan port health check.
+var degradedDecisions = expvar.NewMap("degraded_decisions")
+
+// knownValue ialah hasil carian terakhir yang berjaya bagi seorang user pada masa At
+type knownValue struct {
+	UserID int64
+	Value  bool
+	At     time.Time
+}
+
+// knownValues ialah nilai terakhir bagi setiap user, dihadkan kepada LAST_KNOWN_MAX user
+// (paling lama tidak dikemaskini dibuang dahulu) dan LAST_KNOWN_TTL. Dipanggil dengan
+// lastKnown dikunci.
+type knownValues struct {
+	entries map[int64]*list.Element // nilai: *knownValue
+	lru     *list.List              // hadapan = paling baru dikemaskini
+}
+
+func newKnownValues() *knownValues {
+	return &knownValues{entries: make(map[int64]*list.Element), lru: list.New()}
+}
+
+func (k *knownValues) set(userID int64, value bool, now time.Time) {
+	if el, ok := k.entries[userID]; ok {
+		v := el.Value.(*knownValue)
+		v.Value, v.At = value, now
+		k.lru.MoveToFront(el)
+		return
+	}
+	if config.LastKnownMax > 0 && k.lru.Len() >= config.LastKnownMax {
+		k.remove(k.lru.Back())
+	}
+	k.entries[userID] = k.lru.PushFront(&knownValue{UserID: userID, Value: value, At: now})
+}
+
+// get memulangkan nilai terakhir yang belum melepasi LAST_KNOWN_TTL
+func (k *knownValues) get(userID int64, now time.Time) (bool, bool) {
+	el, ok := k.entries[userID]
+	if !ok {
+		return false, false
+	}
+	v := el.Value.(*knownValue)
+	if config.LastKnownTTL > 0 && now.Sub(v.At) >= config.LastKnownTTL {
+		return false, false
+	}
+	return v.Value, true
+}
+
+func (k *knownValues) delete(userID int64) {
+	if el, ok := k.entries[userID]; ok {
+		k.remove(el)
+	}
+}
+
+func (k *knownValues) remove(el *list.Element) {
+	k.lru.Remove(el)
+	delete(k.entries, el.Value.(*knownValue).UserID)
+}
+
+// sweep membuang nilai yang dikemaskini sebelum cutoff dan memulangkan bilangannya
+func (k *knownValues) sweep(cutoff time.Time) int {
+	removed := 0
+	for el := k.lru.Back(); el != nil && el.Value.(*knownValue).At.Before(cutoff); el = k.lru.Back() {
+		k.remove(el)
+		removed++
+	}
+	return removed
+}
+
+// lastKnown menyimpan hasil carian terakhir yang berjaya untuk setiap user
+var lastKnown = struct {
+	sync.Mutex
+	banned *knownValues
+	agreed *knownValues
+}{banned: newKnownValues(), agreed: newKnownValues()}
+
+// sweepLastKnown membuang nilai terakhir yang melepasi LAST_KNOWN_TTL (dipanggil oleh pembersih)
+func sweepLastKnown(now time.Time) int {
+	if config.LastKnownTTL <= 0 {
+		return 0
+	}
+	cutoff := now.Add(-config.LastKnownTTL)
+	lastKnown.Lock()
+	defer lastKnown.Unlock()
+	return lastKnown.banned.sweep(cutoff) + lastKnown.agreed.sweep(cutoff)
+}
+
+// BannedWithPolicy memulangkan status sekatan; jika storan gagal, polisi BAN_LOOKUP_POLICY digunakan
+func BannedWithPolicy(userID int64) bool {
+	banned, err := IsBanned(userID)
+	if err == nil {
+		lastKnown.Lock()
+		lastKnown.banned.set(userID, banned, time.Now())
+		lastKnown.Unlock()
+		return banned
+	}
+	return degradedDecision("ban", config.BanLookupPolicy, userID, err, lastKnown.banned, true)
+}
+
+// AgreedWithPolicy memulangkan status persetujuan; jika storan gagal, polisi AGREEMENT_LOOKUP_POLICY digunakan
+func AgreedWithPolicy(userID int64) bool {
+	agreed, err := HasAgreed(userID)
+	if err == nil {
+		lastKnown.Lock()
+		lastKnown.agreed.set(userID, agreed, time.Now())
+		lastKnown.Unlock()
+		return agreed
+	}
+	return degradedDecision("agreement", config.AgreementLookupPolicy, userID, err, lastKnown.agreed, false)
+}
+
+// degradedDecision memilih keputusan mengikut polisi, kemudian log dan kira keputusan tersebut.
+// closedValue ialah nilai "selamat" untuk semakan ini (true untuk sekatan, false untuk persetujuan).
+func degradedDecision(check, policy string, userID int64, err error, known *knownValues, closedValue bool) bool {
+	decision := closedValue
+	source := policyFailClosed
+
+	switch policy {
+	case policyFailOpen:
+		decision, source = !closedValue, policyFailOpen
+	case policyLastKnown:
+		lastKnown.Lock()
+		v, ok := known.get(userID, time.Now())
+		lastKnown.Unlock()
+		if ok {
+			decision, source = v, policyLastKnown
+		}
+	}
+
+	degradedDecisions.Add(check+"_"+source, 1)
+	log.Printf("⚠️ Keputusan terdegradasi (%s, polisi %s -> %s) untuk user %d: %v = %t",
+		check, policy, source, userID, err, decision)
+	return decision
+}
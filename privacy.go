@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// UserDataExport ialah semua data yang disimpan tentang seorang user (untuk /data_saya)
+type UserDataExport struct {
+	UserID      int64            `json:"user_id"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Agreement   *AgreementRecord `json:"agreement"`
+	Ban         *BanRecord       `json:"ban"`
+	// Strikes ialah sejarah kesalahan anti-spam yang disimpan (nil jika tiada)
+	Strikes *StrikeRecord `json:"strikes"`
+	// Activity ialah aktiviti terakhir & sejarah panduan sejak bot dimulakan (nil jika tiada)
+	Activity *userActivityInfo `json:"activity"`
+	// Audit ialah rekod log audit yang melibatkan user (sebagai sasaran atau pelaku)
+	Audit []AuditRecord `json:"audit"`
+}
+
+// WithdrawAgreement memadam rekod persetujuan user (PDPA: penarikan balik persetujuan)
+func WithdrawAgreement(userID int64) error {
+	if err := store.DeleteAgreement(userID); err != nil {
+		return err
+	}
+	// Nilai terakhir tidak boleh lagi digunakan untuk meluluskan user ini
+	lastKnown.Lock()
+	lastKnown.agreed.delete(userID)
+	lastKnown.Unlock()
+	return nil
+}
+
+// ExportUserData mengumpul semua rekod user dalam satu dokumen JSON
+func ExportUserData(userID int64) ([]byte, error) {
+	agreement, err := store.GetAgreement(userID)
+	if err != nil {
+		return nil, fmt.Errorf("gagal baca rekod persetujuan: %v", err)
+	}
+	ban, err := store.GetBan(userID)
+	if err != nil {
+		return nil, fmt.Errorf("gagal baca rekod sekatan: %v", err)
+	}
+	strikes, err := store.GetStrikes(userID)
+	if err != nil {
+		return nil, fmt.Errorf("gagal baca sejarah kesalahan spam: %v", err)
+	}
+	audit, err := readAudit(auditFilter{UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("gagal baca log audit: %v", err)
+	}
+
+	export := UserDataExport{
+		UserID:      userID,
+		GeneratedAt: time.Now(),
+		Agreement:   agreement,
+		Ban:         ban,
+		Strikes:     strikes,
+		Audit:       audit,
+	}
+	if activity, ok := activityOf(userID); ok {
+		export.Activity = &activity
+	}
+	return json.MarshalIndent(export, "", "  ")
+}
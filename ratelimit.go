@@ -0,0 +1,265 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ActionKind ialah jenis tindakan user yang dikenakan kos berbeza oleh pengehad kadar
+type ActionKind string
+
+const (
+	ActionCallback ActionKind = "callback"
+	ActionText     ActionKind = "text"
+	ActionMedia    ActionKind = "media"
+	ActionCommand  ActionKind = "command"
+)
+
+// actionKinds ialah semua jenis tindakan yang dikenali (susunan paparan)
+var actionKinds = []ActionKind{ActionCallback, ActionText, ActionMedia, ActionCommand}
+
+// defaultActionCosts: media (voice, gambar, fail) lebih mahal daripada butang & teks
+var defaultActionCosts = map[ActionKind]float64{
+	ActionCallback: 1,
+	ActionText:     1,
+	ActionMedia:    2,
+	ActionCommand:  1,
+}
+
+// actionKindOf menentukan jenis tindakan bagi satu update
+func actionKindOf(update tgbotapi.Update) ActionKind {
+	msg := update.Message
+	switch {
+	case update.CallbackQuery != nil || msg == nil:
+		return ActionCallback
+	case msg.IsCommand():
+		return ActionCommand
+	case msg.Voice != nil || msg.Photo != nil || msg.Video != nil || msg.Document != nil ||
+		msg.Audio != nil || msg.Sticker != nil || msg.Animation != nil || msg.VideoNote != nil:
+		return ActionMedia
+	}
+	return ActionText
+}
+
+// Clock ialah sumber masa pengehad kadar (boleh diganti supaya tingkah laku deterministik)
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// RateLimit ialah had token bucket: Burst token penuh, satu token diisi semula setiap Refill
+type RateLimit struct {
+	Burst  float64
+	Refill time.Duration
+	Costs  map[ActionKind]float64
+}
+
+// Cost memulangkan kos satu tindakan (jenis tidak dikenali: kos 1)
+func (l RateLimit) Cost(kind ActionKind) float64 {
+	if c, ok := l.Costs[kind]; ok {
+		return c
+	}
+	return 1
+}
+
+// FullAfter ialah masa untuk baki kosong diisi penuh semula. Bucket yang tidak
+// digunakan selama ini sama dengan bucket baharu, jadi boleh dibuang tanpa kesan.
+func (l RateLimit) FullAfter() time.Duration {
+	return time.Duration(l.Burst * float64(l.Refill))
+}
+
+// tokenBucket ialah baki token seorang user pada masa Updated
+type tokenBucket struct {
+	UserID  int64
+	Tokens  float64
+	Updated time.Time
+}
+
+// RateLimiterStats ialah kiraan dalaman pengehad kadar (untuk metrik)
+type RateLimiterStats struct {
+	Tracked     int   `json:"tracked"`
+	MaxTracked  int   `json:"max_tracked"`
+	EvictedLRU  int64 `json:"evicted_lru"`
+	EvictedIdle int64 `json:"evicted_idle"`
+}
+
+// RateLimiter ialah pengehad kadar token bucket bagi setiap user. Token diisi semula
+// secara berterusan, jadi penggunaan biasa yang sekata tidak pernah mencapai had;
+// hanya letusan yang melebihi Burst dalam masa singkat ditolak.
+//
+// Bilangan user yang dijejak dihadkan kepada maxEntries: user yang paling lama tidak
+// aktif dibuang dahulu (LRU). Sweep membuang bucket yang telah penuh semula.
+type RateLimiter struct {
+	mu         sync.Mutex
+	clock      Clock
+	limit      RateLimit
+	maxEntries int
+	buckets    map[int64]*list.Element // nilai: *tokenBucket
+	lru        *list.List              // hadapan = paling baru digunakan
+	stats      RateLimiterStats
+}
+
+// NewRateLimiter membina pengehad kadar; maxEntries <= 0 bermaksud tiada had dan
+// clock nil bermaksud masa sistem
+func NewRateLimiter(limit RateLimit, maxEntries int, clock Clock) *RateLimiter {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &RateLimiter{
+		clock:      clock,
+		limit:      limit,
+		maxEntries: maxEntries,
+		buckets:    make(map[int64]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// refill mengemaskini baki token hingga masa now (dipanggil dengan mu dikunci)
+func (r *RateLimiter) refill(b *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(b.Updated); elapsed > 0 && r.limit.Refill > 0 {
+		b.Tokens += float64(elapsed) / float64(r.limit.Refill)
+		if b.Tokens > r.limit.Burst {
+			b.Tokens = r.limit.Burst
+		}
+	}
+	b.Updated = now
+}
+
+// Allow menolak token mengikut kos tindakan. Memulangkan false (tanpa menolak token)
+// jika baki tidak mencukupi.
+func (r *RateLimiter) Allow(userID int64, kind ActionKind) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.clock.Now()
+	var b *tokenBucket
+	if el, ok := r.buckets[userID]; ok {
+		r.lru.MoveToFront(el)
+		b = el.Value.(*tokenBucket)
+	} else {
+		if r.maxEntries > 0 && r.lru.Len() >= r.maxEntries {
+			r.remove(r.lru.Back())
+			r.stats.EvictedLRU++
+		}
+		b = &tokenBucket{UserID: userID, Tokens: r.limit.Burst, Updated: now}
+		r.buckets[userID] = r.lru.PushFront(b)
+	}
+	r.refill(b, now)
+
+	cost := r.limit.Cost(kind)
+	if b.Tokens < cost {
+		return false
+	}
+	b.Tokens -= cost
+	return true
+}
+
+// Tokens memulangkan baki token semasa seorang user (false jika tiada rekod)
+func (r *RateLimiter) Tokens(userID int64) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	el, ok := r.buckets[userID]
+	if !ok {
+		return r.limit.Burst, false
+	}
+	cp := *el.Value.(*tokenBucket)
+	r.refill(&cp, r.clock.Now())
+	return cp.Tokens, true
+}
+
+// Reset membuang rekod seorang user (baki kembali penuh)
+func (r *RateLimiter) Reset(userID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if el, ok := r.buckets[userID]; ok {
+		r.remove(el)
+	}
+}
+
+// remove membuang satu bucket (dipanggil dengan mu dikunci)
+func (r *RateLimiter) remove(el *list.Element) {
+	r.lru.Remove(el)
+	delete(r.buckets, el.Value.(*tokenBucket).UserID)
+}
+
+// Sweep membuang bucket yang tidak digunakan sejak FullAfter (baki telah penuh semula)
+// dan memulangkan bilangan yang dibuang
+func (r *RateLimiter) Sweep() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cutoff := r.clock.Now().Add(-r.limit.FullAfter())
+	removed := 0
+	// Senarai disusun mengikut penggunaan terakhir: berhenti pada bucket aktif pertama
+	for el := r.lru.Back(); el != nil; el = r.lru.Back() {
+		if el.Value.(*tokenBucket).Updated.After(cutoff) {
+			break
+		}
+		r.remove(el)
+		removed++
+	}
+	r.stats.EvictedIdle += int64(removed)
+	return removed
+}
+
+// Stats memulangkan kiraan dalaman semasa
+func (r *RateLimiter) Stats() RateLimiterStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.stats
+	st.Tracked = r.lru.Len()
+	st.MaxTracked = r.maxEntries
+	return st
+}
+
+// SetLimit menukar had tanpa membuang bucket sedia ada (baki dipotong kepada Burst baharu)
+func (r *RateLimiter) SetLimit(limit RateLimit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limit = limit
+	for el := r.lru.Front(); el != nil; el = el.Next() {
+		if b := el.Value.(*tokenBucket); b.Tokens > limit.Burst {
+			b.Tokens = limit.Burst
+		}
+	}
+}
+
+// Limit memulangkan had semasa
+func (r *RateLimiter) Limit() RateLimit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.limit
+}
+
+// parseActionCosts membaca "callback:1,text:1,media:2,command:1"; jenis yang tidak
+// disebut menggunakan kos lalai
+func parseActionCosts(s string) (map[ActionKind]float64, error) {
+	costs := make(map[ActionKind]float64, len(defaultActionCosts))
+	for k, v := range defaultActionCosts {
+		costs[k] = v
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, ":")
+		kind := ActionKind(strings.ToLower(strings.TrimSpace(name)))
+		if _, known := defaultActionCosts[kind]; !ok || !known {
+			return nil, fmt.Errorf("entri kos tidak sah: %q", part)
+		}
+		cost, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("kos tidak sah untuk %s: %q", kind, value)
+		}
+		costs[kind] = cost
+	}
+	return costs, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock ialah Clock yang hanya bergerak apabila Advance dipanggil
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func testLimit() RateLimit {
+	return RateLimit{Burst: 5, Refill: 3 * time.Second, Costs: defaultActionCosts}
+}
+
+func TestRateLimiterBurstExhaustion(t *testing.T) {
+	clock := newFakeClock()
+	rl := NewRateLimiter(testLimit(), 0, clock)
+
+	for i := 0; i < 5; i++ {
+		if !rl.Allow(1, ActionCallback) {
+			t.Fatalf("tindakan %d ditolak dalam had burst", i+1)
+		}
+	}
+	if rl.Allow(1, ActionCallback) {
+		t.Fatal("tindakan melebihi burst sepatutnya ditolak")
+	}
+	// Penolakan tidak menolak token
+	if tokens, _ := rl.Tokens(1); tokens != 0 {
+		t.Fatalf("baki = %v, mahu 0", tokens)
+	}
+	// User lain mempunyai bucket sendiri
+	if !rl.Allow(2, ActionCallback) {
+		t.Fatal("user lain tidak sepatutnya terjejas")
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	clock := newFakeClock()
+	rl := NewRateLimiter(testLimit(), 0, clock)
+
+	for i := 0; i < 5; i++ {
+		rl.Allow(1, ActionCallback)
+	}
+	clock.Advance(2999 * time.Millisecond)
+	if rl.Allow(1, ActionCallback) {
+		t.Fatal("token belum diisi semula sebelum Refill")
+	}
+	clock.Advance(time.Millisecond)
+	if !rl.Allow(1, ActionCallback) {
+		t.Fatal("satu token sepatutnya diisi semula selepas Refill")
+	}
+
+	// Baki tidak melebihi Burst walaupun lama tidak aktif
+	clock.Advance(time.Hour)
+	if tokens, _ := rl.Tokens(1); tokens != 5 {
+		t.Fatalf("baki = %v, mahu 5 (Burst)", tokens)
+	}
+}
+
+func TestRateLimiterSteadyButtonCadence(t *testing.T) {
+	clock := newFakeClock()
+	// Had lalai (SPAM_BURST=6, SPAM_REFILL=1s)
+	rl := NewRateLimiter(RateLimit{Burst: 6, Refill: time.Second, Costs: defaultActionCosts}, 0, clock)
+
+	// Tekan butang setiap 2.9s selama sejam: CheckSpam lama akhirnya menyekat
+	// penggunaan biasa ini, pengehad token bucket tidak pernah menolaknya
+	for elapsed := time.Duration(0); elapsed < time.Hour; elapsed += 2900 * time.Millisecond {
+		if !rl.Allow(1, ActionCallback) {
+			t.Fatalf("tekanan ditolak selepas %s pada kadar 2.9s", elapsed)
+		}
+		clock.Advance(2900 * time.Millisecond)
+	}
+
+	// Pada kadar tepat Refill pun tiada had langsung
+	rl.Reset(1)
+	for i := 0; i < 10000; i++ {
+		if !rl.Allow(1, ActionCallback) {
+			t.Fatalf("tekanan %d ditolak pada kadar Refill", i+1)
+		}
+		clock.Advance(time.Second)
+	}
+}
+
+func TestRateLimiterActionCosts(t *testing.T) {
+	clock := newFakeClock()
+	costs, err := parseActionCosts("media:2,command:0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := NewRateLimiter(RateLimit{Burst: 5, Refill: time.Second, Costs: costs}, 0, clock)
+
+	// 2 media = 4 token, baki 1: media ketiga ditolak tetapi teks masih dibenarkan
+	rl.Allow(1, ActionMedia)
+	rl.Allow(1, ActionMedia)
+	if rl.Allow(1, ActionMedia) {
+		t.Fatal("media sepatutnya ditolak dengan baki 1")
+	}
+	if !rl.Allow(1, ActionText) {
+		t.Fatal("teks (kos 1) sepatutnya dibenarkan dengan baki 1")
+	}
+
+	// Arahan berkos 0.5: 10 arahan muat dalam burst 5
+	for i := 0; i < 10; i++ {
+		if !rl.Allow(2, ActionCommand) {
+			t.Fatalf("arahan %d ditolak", i+1)
+		}
+	}
+	if rl.Allow(2, ActionCommand) {
+		t.Fatal("arahan ke-11 sepatutnya ditolak")
+	}
+
+	// Jenis tidak dikenali berkos 1
+	if got := (RateLimit{}).Cost("lain"); got != 1 {
+		t.Fatalf("kos jenis tidak dikenali = %v, mahu 1", got)
+	}
+	if _, err := parseActionCosts("sticker:3"); err == nil {
+		t.Fatal("jenis tidak dikenali sepatutnya ditolak oleh parseActionCosts")
+	}
+}
+
+func TestRateLimiterLRUEviction(t *testing.T) {
+	clock := newFakeClock()
+	rl := NewRateLimiter(testLimit(), 2, clock)
+
+	rl.Allow(1, ActionCallback)
+	rl.Allow(2, ActionCallback)
+	rl.Allow(1, ActionCallback) // user 1 kini paling baru digunakan
+	rl.Allow(3, ActionCallback) // user 2 dibuang
+
+	if _, ok := rl.Tokens(2); ok {
+		t.Fatal("user 2 (paling lama tidak aktif) sepatutnya dibuang")
+	}
+	for _, id := range []int64{1, 3} {
+		if _, ok := rl.Tokens(id); !ok {
+			t.Fatalf("user %d sepatutnya masih dijejak", id)
+		}
+	}
+	st := rl.Stats()
+	if st.Tracked != 2 || st.MaxTracked != 2 || st.EvictedLRU != 1 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	clock := newFakeClock()
+	rl := NewRateLimiter(testLimit(), 0, clock) // FullAfter = 15s
+
+	rl.Allow(1, ActionCallback)
+	clock.Advance(10 * time.Second)
+	rl.Allow(2, ActionCallback)
+
+	clock.Advance(5 * time.Second) // user 1: 15s tidak aktif, user 2: 5s
+	if n := rl.Sweep(); n != 1 {
+		t.Fatalf("Sweep membuang %d, mahu 1", n)
+	}
+	if _, ok := rl.Tokens(1); ok {
+		t.Fatal("user 1 sepatutnya dibuang oleh Sweep")
+	}
+	if _, ok := rl.Tokens(2); !ok {
+		t.Fatal("user 2 masih aktif dan tidak sepatutnya dibuang")
+	}
+	if st := rl.Stats(); st.EvictedIdle != 1 || st.Tracked != 1 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestRateLimiterSetLimitClampsBuckets(t *testing.T) {
+	clock := newFakeClock()
+	rl := NewRateLimiter(testLimit(), 0, clock)
+
+	rl.Allow(1, ActionCallback) // baki 4
+	for i := 0; i < 5; i++ {
+		rl.Allow(2, ActionCallback) // baki 0
+	}
+
+	rl.SetLimit(RateLimit{Burst: 2, Refill: time.Second, Costs: defaultActionCosts})
+	if tokens, _ := rl.Tokens(1); tokens != 2 {
+		t.Fatalf("baki user 1 = %v, mahu dipotong kepada 2", tokens)
+	}
+	if tokens, _ := rl.Tokens(2); tokens != 0 {
+		t.Fatalf("baki user 2 = %v, mahu kekal 0", tokens)
+	}
+	if got := rl.Limit().Burst; got != 2 {
+		t.Fatalf("Limit().Burst = %v, mahu 2", got)
+	}
+	// Refill baharu berkuat kuasa serta-merta
+	clock.Advance(time.Second)
+	if !rl.Allow(2, ActionCallback) {
+		t.Fatal("user 2 sepatutnya mendapat satu token selepas Refill baharu")
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Role menentukan kebenaran seorang user. Setiap peranan mewarisi kebenaran peranan di bawahnya.
+type Role int
+
+const (
+	RoleUser Role = iota
+	RoleSupport
+	RoleModerator
+	RoleOwner
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleSupport:
+		return "Support"
+	case RoleModerator:
+		return "Moderator"
+	case RoleOwner:
+		return "Owner"
+	}
+	return "User"
+}
+
+// parseRole menukar nama peranan dalam konfigurasi kepada Role
+func parseRole(s string) (Role, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "owner":
+		return RoleOwner, true
+	case "moderator", "mod":
+		return RoleModerator, true
+	case "support":
+		return RoleSupport, true
+	}
+	return RoleUser, false
+}
+
+// Permission ialah satu tindakan yang memerlukan kebenaran
+type Permission string
+
+const (
+	// PermUse: guna bot seperti biasa (semua user)
+	PermUse Permission = "use"
+	// PermBypassChecks: dikecualikan dari Terma, anti-spam, sekatan dan penapis teks
+	PermBypassChecks Permission = "bypass_checks"
+	PermBan          Permission = "ban"
+	PermUnban        Permission = "unban"
+	// PermAppeals: menerima dan memutuskan rayuan sekatan
+	PermAppeals Permission = "appeals"
+	// PermAudit: membaca dan mengeksport log audit
+	PermAudit     Permission = "audit"
+	PermStats     Permission = "stats"
+	PermWhois     Permission = "whois"
+	PermBroadcast Permission = "broadcast"
+	// PermAntiSpam: menukar tetapan anti-spam semasa bot berjalan
+	PermAntiSpam Permission = "antispam"
+	// PermSystemAlerts: menerima amaran sistem (storan, integriti terma, dll.)
+	PermSystemAlerts Permission = "system_alerts"
+)
+
+// permissionRoles ialah peranan minimum bagi setiap kebenaran
+var permissionRoles = map[Permission]Role{
+	PermUse:          RoleUser,
+	PermBypassChecks: RoleSupport,
+	PermBan:          RoleModerator,
+	PermUnban:        RoleModerator,
+	PermAppeals:      RoleModerator,
+	PermAudit:        RoleModerator,
+	PermStats:        RoleSupport,
+	PermWhois:        RoleSupport,
+	PermBroadcast:    RoleOwner,
+	PermAntiSpam:     RoleOwner,
+	PermSystemAlerts: RoleOwner,
+}
+
+// MinRole memulangkan peranan minimum untuk kebenaran (kebenaran tidak dikenali: Owner sahaja)
+func (p Permission) MinRole() Role {
+	if r, ok := permissionRoles[p]; ok {
+		return r
+	}
+	return RoleOwner
+}
+
+// Can menyemak sama ada peranan mempunyai kebenaran
+func (r Role) Can(p Permission) bool {
+	return r >= p.MinRole()
+}
+
+// StaffMember ialah seorang ahli pasukan moderasi (dari konfigurasi ADMINS)
+type StaffMember struct {
+	UserID int64
+	Role   Role
+	Name   string
+}
+
+// RoleOf memulangkan peranan user mengikut konfigurasi
+func RoleOf(userID int64) Role {
+	for _, m := range config.Staff {
+		if m.UserID == userID {
+			return m.Role
+		}
+	}
+	return RoleUser
+}
+
+// HasPermission menyemak sama ada user mempunyai kebenaran
+func HasPermission(userID int64, p Permission) bool {
+	return RoleOf(userID).Can(p)
+}
+
+// StaffName memulangkan nama paparan pentadbir untuk rekod sekatan & audit
+func StaffName(userID int64, username string) string {
+	for _, m := range config.Staff {
+		if m.UserID == userID && m.Name != "" {
+			return m.Name
+		}
+	}
+	if username != "" {
+		return "@" + username
+	}
+	return fmt.Sprintf("ID %d", userID)
+}
+
+// staffWith memulangkan ID semua ahli pasukan yang mempunyai kebenaran
+func staffWith(p Permission) []int64 {
+	var ids []int64
+	for _, m := range config.Staff {
+		if m.Role.Can(p) {
+			ids = append(ids, m.UserID)
+		}
+	}
+	return ids
+}
+
+// notifyStaff menghantar mesej Markdown kepada semua ahli pasukan yang mempunyai kebenaran
+func notifyStaff(bot *tgbotapi.BotAPI, p Permission, text string) {
+	for _, id := range staffWith(p) {
+		msg := tgbotapi.NewMessage(id, text)
+		msg.ParseMode = tgbotapi.ModeMarkdown
+		sendToStaff(bot, id, msg)
+	}
+}
+
+// sendToStaff menghantar mesej kepada seorang ahli pasukan dan log jika gagal
+func sendToStaff(bot *tgbotapi.BotAPI, id int64, msg tgbotapi.Chattable) {
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("Gagal hantar notis kepada staf %d: %v", id, err)
+	}
+}
+
+// parseStaff membaca senarai "id:peranan[:nama]" dipisahkan koma.
+// Entri tidak sah dilog dan diabaikan.
+func parseStaff(v string) []StaffMember {
+	var staff []StaffMember
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 2 {
+			log.Printf("⚠️ Entri ADMINS tidak sah (%q), diabaikan", entry)
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+		if err != nil {
+			log.Printf("⚠️ ID dalam ADMINS tidak sah (%q), diabaikan", entry)
+			continue
+		}
+		role, ok := parseRole(parts[1])
+		if !ok {
+			log.Printf("⚠️ Peranan dalam ADMINS tidak sah (%q), diabaikan", entry)
+			continue
+		}
+		m := StaffMember{UserID: id, Role: role}
+		if len(parts) == 3 {
+			m.Name = strings.TrimSpace(parts[2])
+		}
+		staff = append(staff, m)
+	}
+	return staff
+}
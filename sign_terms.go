@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runSignTerms melaksanakan subcommand "sign-terms":
+//
+//	telebot sign-terms -genkey -key terms.key
+//	telebot sign-terms -key terms.key [-in terms.json] [-out terms.json]
+//
+// Kunci persendirian disimpan dalam base64 (seed 32 bait atau kunci penuh 64 bait).
+func runSignTerms(args []string) int {
+	fs := flag.NewFlagSet("sign-terms", flag.ContinueOnError)
+	keyPath := fs.String("key", "", "fail kunci persendirian ed25519 (base64)")
+	in := fs.String("in", "terms.json", "fail terma untuk ditandatangani")
+	out := fs.String("out", "", "fail output (lalai: tulis ganti -in)")
+	genKey := fs.Bool("genkey", false, "jana pasangan kunci baharu ke fail -key")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *keyPath == "" {
+		fmt.Fprintln(os.Stderr, "❌ -key mesti ditetapkan")
+		return 2
+	}
+
+	if *genKey {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Gagal jana kunci: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(*keyPath, []byte(base64.StdEncoding.EncodeToString(priv.Seed())+"\n"), 0o600); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Gagal simpan kunci: %v\n", err)
+			return 1
+		}
+		fmt.Printf("✅ Kunci persendirian disimpan ke %s\n", *keyPath)
+		fmt.Printf("TERMS_PUBLIC_KEY=%s\n", base64.StdEncoding.EncodeToString(pub))
+		return 0
+	}
+
+	keyData, err := os.ReadFile(*keyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Gagal baca kunci: %v\n", err)
+		return 1
+	}
+	raw, err := parseTermsKey(string(keyData))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	var priv ed25519.PrivateKey
+	switch len(raw) {
+	case ed25519.SeedSize:
+		priv = ed25519.NewKeyFromSeed(raw)
+	case ed25519.PrivateKeySize:
+		priv = ed25519.PrivateKey(raw)
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Saiz kunci tidak sah: %d bait\n", len(raw))
+		return 1
+	}
+
+	body, err := os.ReadFile(*in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Gagal baca %s: %v\n", *in, err)
+		return 1
+	}
+	signed, err := signTerms(body, priv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	if *out == "" {
+		*out = *in
+	}
+	if err := os.WriteFile(*out, signed, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Gagal tulis %s: %v\n", *out, err)
+		return 1
+	}
+	fmt.Printf("✅ %s ditandatangani (kunci awam: %s)\n", *out, base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey)))
+	return 0
+}
+
+// signTerms mengemaskini blok integrity_check dan mengekalkan susunan kunci asal fail
+func signTerms(body []byte, priv ed25519.PrivateKey) ([]byte, error) {
+	canonical, err := canonicalTermsJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(canonical)
+	integrity, _ := json.Marshal(IntegrityCheck{
+		HashAlgorithm:      "SHA-256",
+		SignatureAlgorithm: "Ed25519",
+		Hash:               strings.ToUpper(hex.EncodeToString(sum[:])),
+		Signature:          base64.StdEncoding.EncodeToString(ed25519.Sign(priv, canonical)),
+	})
+
+	keys, values, err := topLevelFields(body)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := values["integrity_check"]; !ok {
+		keys = append(keys, "integrity_check")
+	}
+	values["integrity_check"] = integrity
+
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		name, _ := json.Marshal(k)
+		buf.Write(name)
+		buf.WriteString(":")
+		buf.Write(values[k])
+	}
+	buf.WriteString("}")
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	pretty.WriteString("\n")
+	return pretty.Bytes(), nil
+}
+
+// topLevelFields memulangkan kunci peringkat atas mengikut susunan asal berserta nilainya
+func topLevelFields(body []byte) ([]string, map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("terms.json mesti objek JSON")
+	}
+	var keys []string
+	values := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, _ := tok.(string)
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		if _, dup := values[key]; !dup {
+			keys = append(keys, key)
+		}
+		values[key] = v
+	}
+	return keys, values, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Nama kaunter statistik
+const (
+	statStart        = "start"
+	statAgreed       = "agreed"
+	statBanManual    = "ban_manual"
+	statBanAuto      = "ban_auto"
+	statSpamDetected = "spam_detected"
+	statGuideClaim   = "guide_claim"
+	statGuideWallet  = "guide_wallet"
+	statGuideCashout = "guide_cashout"
+	statGuideWebsite = "guide_website"
+	statInfographic  = "infographic"
+	statReset        = "reset"
+)
+
+// statsRetentionDays ialah bilangan hari kiraan harian yang disimpan
+const statsRetentionDays = 31
+
+// botStats menyimpan kaunter penggunaan bot (jumlah & harian).
+// Disimpan ke STATE_DIR/stats.json secara berkala supaya kekal selepas restart.
+var botStats = struct {
+	sync.Mutex
+	Totals map[string]int64            `json:"totals"`
+	Daily  map[string]map[string]int64 `json:"daily"` // "2006-01-02" -> kaunter -> kiraan
+	dirty  bool
+}{Totals: make(map[string]int64), Daily: make(map[string]map[string]int64)}
+
+func init() {
+	// Jumlah kaunter turut tersedia di /debug/vars
+	expvar.Publish("bot_stats", expvar.Func(func() interface{} {
+		botStats.Lock()
+		defer botStats.Unlock()
+		totals := make(map[string]int64, len(botStats.Totals))
+		for k, v := range botStats.Totals {
+			totals[k] = v
+		}
+		return totals
+	}))
+}
+
+func statsDay(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// countStat menambah satu pada kaunter (jumlah dan hari ini)
+func countStat(name string) {
+	botStats.Lock()
+	defer botStats.Unlock()
+	botStats.Totals[name]++
+	day := statsDay(time.Now())
+	if botStats.Daily[day] == nil {
+		botStats.Daily[day] = make(map[string]int64)
+	}
+	botStats.Daily[day][name]++
+	botStats.dirty = true
+}
+
+// statsOn memulangkan jumlah dan kiraan hari tertentu bagi satu kaunter
+func statsOn(name string, day string) (total, daily int64) {
+	botStats.Lock()
+	defer botStats.Unlock()
+	return botStats.Totals[name], botStats.Daily[day][name]
+}
+
+func statsPath() string {
+	return filepath.Join(config.StateDir, "stats.json")
+}
+
+// loadStats membaca kaunter yang disimpan; fail yang tiada bermaksud mula dari kosong
+func loadStats() {
+	data, err := os.ReadFile(statsPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("⚠️ Gagal baca statistik: %v", err)
+		}
+		return
+	}
+	botStats.Lock()
+	defer botStats.Unlock()
+	if err := json.Unmarshal(data, &botStats); err != nil {
+		log.Printf("⚠️ Gagal parse statistik: %v", err)
+	}
+	if botStats.Totals == nil {
+		botStats.Totals = make(map[string]int64)
+	}
+	if botStats.Daily == nil {
+		botStats.Daily = make(map[string]map[string]int64)
+	}
+}
+
+// flushStats menulis kaunter ke cakera jika ada perubahan (tmp + rename)
+func flushStats() error {
+	botStats.Lock()
+	if !botStats.dirty {
+		botStats.Unlock()
+		return nil
+	}
+	// Buang kiraan harian yang melebihi tempoh simpanan
+	cutoff := statsDay(time.Now().AddDate(0, 0, -statsRetentionDays))
+	for day := range botStats.Daily {
+		if day < cutoff {
+			delete(botStats.Daily, day)
+		}
+	}
+	data, err := json.MarshalIndent(&botStats, "", "  ")
+	botStats.dirty = false
+	botStats.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(config.StateDir, 0o755); err != nil {
+		return err
+	}
+	tmp := statsPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, statsPath())
+}
+
+// startStatsFlush menyimpan kaunter ke cakera secara berkala
+func startStatsFlush(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(interval)
+			if err := flushStats(); err != nil {
+				log.Printf("⚠️ Gagal simpan statistik: %v", err)
+				botStats.Lock()
+				botStats.dirty = true
+				botStats.Unlock()
+			}
+		}
+	}()
+}
+
+// BuildStatsReport menghasilkan laporan /stats (teks biasa)
+func BuildStatsReport(now time.Time) string {
+	today := statsDay(now)
+	line := func(label, name string) string {
+		total, daily := statsOn(name, today)
+		return fmt.Sprintf("%s: %d (hari ini +%d)\n", label, total, daily)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📊 STATISTIK BOT (%s)\n\n", now.Format("2006-01-02 15:04")))
+
+	// Jumlah semasa dari storan
+	sb.WriteString("👥 Pengguna\n")
+	if recs, err := store.ListAgreements(); err == nil {
+		_, daily := statsOn(statAgreed, today)
+		sb.WriteString(fmt.Sprintf("Bersetuju: %d (hari ini +%d)\n", len(recs), daily))
+	} else {
+		sb.WriteString(fmt.Sprintf("Bersetuju: ralat storan (%v)\n", err))
+	}
+	if bans, err := store.ListBans(); err == nil {
+		manual, auto := 0, 0
+		for _, b := range bans {
+			if !b.Active(now) {
+				continue
+			}
+			if b.ByAdmin == autoBanActor {
+				auto++
+			} else {
+				manual++
+			}
+		}
+		_, dManual := statsOn(statBanManual, today)
+		_, dAuto := statsOn(statBanAuto, today)
+		sb.WriteString(fmt.Sprintf("Disekat (aktif): %d\n", manual+auto))
+		sb.WriteString(fmt.Sprintf("   Manual: %d (hari ini +%d)\n", manual, dManual))
+		sb.WriteString(fmt.Sprintf("   Auto: %d (hari ini +%d)\n", auto, dAuto))
+	} else {
+		sb.WriteString(fmt.Sprintf("Disekat: ralat storan (%v)\n", err))
+	}
+	sb.WriteString(line("Spam dikesan", statSpamDetected))
+
+	sb.WriteString("\n📈 Penggunaan\n")
+	sb.WriteString(line("/start", statStart))
+	sb.WriteString(line("Panduan Worldcoin", statGuideClaim))
+	sb.WriteString(line("Panduan HATA", statGuideWallet))
+	sb.WriteString(line("Panduan Cashout", statGuideCashout))
+	sb.WriteString(line("Website", statGuideWebsite))
+	sb.WriteString(line("Infografik", statInfographic))
+	sb.WriteString(line("Reset Mesej", statReset))
+
+	return sb.String()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// AgreementRecord ialah rekod persetujuan T&C seorang user
+type AgreementRecord struct {
+	UserID   int64     `json:"user_id"`
+	Username string    `json:"username"`
+	AgreedAt time.Time `json:"agreed_at"`
+	Status   string    `json:"status"`
+	// Versi dan hash SHA-256 terma yang dipersetujui
+	TermsVersion string `json:"terms_version,omitempty"`
+	TermsHash    string `json:"terms_hash,omitempty"`
+	// InactiveSince: user telah block bot / akaun dinyahaktif (dikesan semasa siaran)
+	InactiveSince *time.Time `json:"inactive_since,omitempty"`
+}
+
+// BanRecord ialah rekod sekatan seorang user
+type BanRecord struct {
+	UserID   int64     `json:"user_id"`
+	Reason   string    `json:"reason"`
+	BannedAt time.Time `json:"banned_at"`
+	ByAdmin  string    `json:"by_admin"`
+	// ExpiresAt: tamat sekatan sementara (nil = sekatan kekal)
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// LiftedAt: sekatan ditarik balik (rayuan diluluskan atau tamat tempoh); rekod disimpan
+	// untuk sejarah rayuan dan keputusan. /unban memadam rekod sepenuhnya.
+	LiftedAt *time.Time `json:"lifted_at,omitempty"`
+	// Appeal ialah rayuan user berserta semua keputusan Admin
+	Appeal *BanAppeal `json:"appeal,omitempty"`
+	// History ialah sekatan terdahulu (berserta rayuan & keputusannya) yang digantikan
+	// oleh sekatan ini, terlama dahulu
+	History []BanRecord `json:"history,omitempty"`
+}
+
+// Expired memulangkan true jika sekatan sementara telah tamat pada masa now
+func (r BanRecord) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// Active memulangkan true jika sekatan masih berkuat kuasa pada masa now
+func (r BanRecord) Active(now time.Time) bool {
+	return r.LiftedAt == nil && !r.Expired(now)
+}
+
+// StrikeEvent ialah satu kesalahan anti-spam yang dihukum
+type StrikeEvent struct {
+	At     time.Time  `json:"at"`
+	Action ActionKind `json:"action"`
+	ChatID int64      `json:"chat_id"`
+	// Penalty ialah langkah tangga hukuman yang dikenakan (contoh "warn", "ban:1d")
+	Penalty string `json:"penalty,omitempty"`
+}
+
+// StrikeRecord ialah sejarah kesalahan anti-spam seorang user (dalam tempoh simpanan)
+type StrikeRecord struct {
+	UserID int64         `json:"user_id"`
+	Events []StrikeEvent `json:"events"`
+	// ClearedAt: kesalahan sebelum masa ini tidak lagi dikira untuk tangga hukuman
+	// (contoh: selepas unban), tetapi disimpan untuk semakan Admin
+	ClearedAt *time.Time `json:"cleared_at,omitempty"`
+}
+
+// Store ialah antaramuka storan untuk rekod persetujuan dan sekatan.
+// Get* memulangkan (nil, nil) jika rekod tidak wujud.
+type Store interface {
+	GetAgreement(userID int64) (*AgreementRecord, error)
+	SaveAgreement(rec AgreementRecord) error
+	DeleteAgreement(userID int64) error
+	ListAgreements() ([]AgreementRecord, error)
+
+	GetBan(userID int64) (*BanRecord, error)
+	SaveBan(rec BanRecord) error
+	DeleteBan(userID int64) error
+	ListBans() ([]BanRecord, error)
+
+	GetStrikes(userID int64) (*StrikeRecord, error)
+	SaveStrikes(rec StrikeRecord) error
+	DeleteStrikes(userID int64) error
+	ListStrikes() ([]StrikeRecord, error)
+}
+
+// store ialah backend aktif, dipilih oleh newStore semasa startup
+var store Store
+
+// storeCache ialah lapisan cache di atas backend; digunakan untuk invalidate selepas unban
+var storeCache *cachedStore
+
+// storeQueue ialah jurnal tulisan (write-behind); pekerjanya dimulakan oleh main()
+var storeQueue *writeQueue
+
+// newStore memilih backend storan berdasarkan konfigurasi, kemudian membalutnya dengan
+// jurnal tulisan dan cache: cache -> jurnal -> backend
+func newStore(cfg Config) (Store, error) {
+	backend, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	storeQueue, err = newWriteQueue(backend, filepath.Join(cfg.StateDir, "journal.jsonl"), cfg.WriteMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	storeCache = newCachedStore(storeQueue, cfg.CachePositiveTTL, cfg.CacheNegativeTTL)
+	return storeCache, nil
+}
+
+// newBackend mencipta backend storan mentah mengikut STORAGE_BACKEND
+func newBackend(cfg Config) (Store, error) {
+	switch cfg.StorageBackend {
+	case "github":
+		g := newGithubStore(cfg.GithubToken, cfg.GithubRepo, cfg.GithubBranch, cfg.GithubAPIURL)
+		if cfg.GithubSyncInterval > 0 {
+			// Muat turun senarai penuh sekali; jika gagal, carian satu-satu masih berfungsi
+			if err := g.syncIndex(); err != nil {
+				log.Printf("⚠️ Gagal muat indeks GitHub semasa startup: %v", err)
+			}
+			g.startSync(cfg.GithubSyncInterval)
+		}
+		return g, nil
+	case "fs", "local":
+		return newFSStore(cfg.DataDir)
+	case "sqlite":
+		return newSQLiteStore(cfg.SQLitePath)
+	default:
+		return nil, fmt.Errorf("STORAGE_BACKEND tidak dikenali: %q", cfg.StorageBackend)
+	}
+}
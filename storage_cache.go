@@ -0,0 +1,112 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// cacheEntry menyimpan hasil carian (rekod atau "tiada") bersama masa luputnya
+type cacheEntry[T any] struct {
+	rec     *T
+	expires time.Time
+}
+
+// cachedStore membalut Store lain dengan cache dalam memori.
+// Hasil positif (rekod wujud) dan negatif (tiada rekod) mempunyai TTL berasingan.
+// Ralat tidak pernah di-cache.
+type cachedStore struct {
+	Store
+	positiveTTL time.Duration
+	negativeTTL time.Duration
+
+	mu         sync.Mutex
+	agreements map[int64]cacheEntry[AgreementRecord]
+	bans       map[int64]cacheEntry[BanRecord]
+}
+
+func newCachedStore(backend Store, positiveTTL, negativeTTL time.Duration) *cachedStore {
+	return &cachedStore{
+		Store:       backend,
+		positiveTTL: positiveTTL,
+		negativeTTL: negativeTTL,
+		agreements:  make(map[int64]cacheEntry[AgreementRecord]),
+		bans:        make(map[int64]cacheEntry[BanRecord]),
+	}
+}
+
+func (c *cachedStore) ttl(found bool) time.Duration {
+	if found {
+		return c.positiveTTL
+	}
+	return c.negativeTTL
+}
+
+func (c *cachedStore) GetAgreement(userID int64) (*AgreementRecord, error) {
+	c.mu.Lock()
+	if e, ok := c.agreements[userID]; ok && time.Now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.rec, nil
+	}
+	c.mu.Unlock()
+
+	rec, err := c.Store.GetAgreement(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.agreements[userID] = cacheEntry[AgreementRecord]{rec: rec, expires: time.Now().Add(c.ttl(rec != nil))}
+	c.mu.Unlock()
+	return rec, nil
+}
+
+func (c *cachedStore) GetBan(userID int64) (*BanRecord, error) {
+	c.mu.Lock()
+	if e, ok := c.bans[userID]; ok && time.Now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.rec, nil
+	}
+	c.mu.Unlock()
+
+	rec, err := c.Store.GetBan(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.bans[userID] = cacheEntry[BanRecord]{rec: rec, expires: time.Now().Add(c.ttl(rec != nil))}
+	c.mu.Unlock()
+	return rec, nil
+}
+
+// SaveAgreement menulis ke backend dan membuang cache user serta-merta
+func (c *cachedStore) SaveAgreement(rec AgreementRecord) error {
+	defer c.Invalidate(rec.UserID)
+	return c.Store.SaveAgreement(rec)
+}
+
+// DeleteAgreement membuang dari backend dan membuang cache user serta-merta
+func (c *cachedStore) DeleteAgreement(userID int64) error {
+	defer c.Invalidate(userID)
+	return c.Store.DeleteAgreement(userID)
+}
+
+// SaveBan menulis ke backend dan membuang cache user serta-merta
+func (c *cachedStore) SaveBan(rec BanRecord) error {
+	defer c.Invalidate(rec.UserID)
+	return c.Store.SaveBan(rec)
+}
+
+// DeleteBan membuang dari backend dan membuang cache user serta-merta
+func (c *cachedStore) DeleteBan(userID int64) error {
+	defer c.Invalidate(userID)
+	return c.Store.DeleteBan(userID)
+}
+
+// Invalidate membuang semua entri cache untuk seorang user
+func (c *cachedStore) Invalidate(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.agreements, userID)
+	delete(c.bans, userID)
+}
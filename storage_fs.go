@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// fsStore menyimpan rekod sebagai fail JSON dalam folder agreements/, blacklist/ dan strikes/ tempatan
+type fsStore struct {
+	root string
+	mu   sync.Mutex
+}
+
+func newFSStore(root string) (*fsStore, error) {
+	for _, dir := range []string{"agreements", "blacklist", "strikes"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			return nil, fmt.Errorf("gagal cipta folder %s: %v", dir, err)
+		}
+	}
+	return &fsStore{root: root}, nil
+}
+
+// readJSON membaca fail ke dalam v. found=false jika fail tiada.
+func (s *fsStore) readJSON(path string, v interface{}) (found bool, err error) {
+	data, err := os.ReadFile(filepath.Join(s.root, path))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("gagal parse %s: %v", path, err)
+	}
+	return true, nil
+}
+
+// writeJSON menulis fail secara atomik (tulis ke fail sementara kemudian rename)
+func (s *fsStore) writeJSON(path string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, _ := json.MarshalIndent(v, "", "  ")
+	full := filepath.Join(s.root, path)
+	tmp := full + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, full)
+}
+
+// remove membuang fail; fail yang sudah tiada dianggap berjaya
+func (s *fsStore) remove(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(filepath.Join(s.root, path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// listJSON memulangkan laluan semua fail .json dalam satu folder
+func (s *fsStore) listJSON(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.root, dir))
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths, nil
+}
+
+func (s *fsStore) GetAgreement(userID int64) (*AgreementRecord, error) {
+	var rec AgreementRecord
+	found, err := s.readJSON(fmt.Sprintf("agreements/%d.json", userID), &rec)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *fsStore) SaveAgreement(rec AgreementRecord) error {
+	return s.writeJSON(fmt.Sprintf("agreements/%d.json", rec.UserID), rec)
+}
+
+func (s *fsStore) DeleteAgreement(userID int64) error {
+	return s.remove(fmt.Sprintf("agreements/%d.json", userID))
+}
+
+func (s *fsStore) ListAgreements() ([]AgreementRecord, error) {
+	paths, err := s.listJSON("agreements")
+	if err != nil {
+		return nil, err
+	}
+	var recs []AgreementRecord
+	for _, p := range paths {
+		var rec AgreementRecord
+		if found, err := s.readJSON(p, &rec); err == nil && found {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
+
+func (s *fsStore) GetBan(userID int64) (*BanRecord, error) {
+	var rec BanRecord
+	found, err := s.readJSON(fmt.Sprintf("blacklist/%d.json", userID), &rec)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *fsStore) SaveBan(rec BanRecord) error {
+	return s.writeJSON(fmt.Sprintf("blacklist/%d.json", rec.UserID), rec)
+}
+
+func (s *fsStore) DeleteBan(userID int64) error {
+	return s.remove(fmt.Sprintf("blacklist/%d.json", userID))
+}
+
+func (s *fsStore) ListBans() ([]BanRecord, error) {
+	paths, err := s.listJSON("blacklist")
+	if err != nil {
+		return nil, err
+	}
+	var recs []BanRecord
+	for _, p := range paths {
+		var rec BanRecord
+		if found, err := s.readJSON(p, &rec); err == nil && found {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
+
+func (s *fsStore) GetStrikes(userID int64) (*StrikeRecord, error) {
+	var rec StrikeRecord
+	found, err := s.readJSON(fmt.Sprintf("strikes/%d.json", userID), &rec)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *fsStore) SaveStrikes(rec StrikeRecord) error {
+	return s.writeJSON(fmt.Sprintf("strikes/%d.json", rec.UserID), rec)
+}
+
+func (s *fsStore) DeleteStrikes(userID int64) error {
+	return s.remove(fmt.Sprintf("strikes/%d.json", userID))
+}
+
+func (s *fsStore) ListStrikes() ([]StrikeRecord, error) {
+	paths, err := s.listJSON("strikes")
+	if err != nil {
+		return nil, err
+	}
+	var recs []StrikeRecord
+	for _, p := range paths {
+		var rec StrikeRecord
+		if found, err := s.readJSON(p, &rec); err == nil && found {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
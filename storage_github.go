@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// githubStore menyimpan rekod sebagai fail JSON dalam repo GitHub (tingkah laku asal bot)
+type githubStore struct {
+	token   string
+	repo    string
+	branch  string
+	baseURL string
+	client  *http.Client
+	index   *githubIndex
+}
+
+func newGithubStore(token, repo, branch, baseURL string) *githubStore {
+	return &githubStore{
+		token:   token,
+		repo:    repo,
+		branch:  branch,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Second},
+		index:   newGithubIndex(),
+	}
+}
+
+func (g *githubStore) contentsURL(path string) string {
+	return fmt.Sprintf("%s/repos/%s/contents/%s", g.baseURL, g.repo, path)
+}
+
+// contentsRefURL ialah URL bacaan API contents pada cawangan yang dikonfigurasi
+// (tanpa ref, GitHub membaca cawangan lalai repo)
+func (g *githubStore) contentsRefURL(path string) string {
+	return g.contentsURL(path) + "?ref=" + url.QueryEscape(g.branch)
+}
+
+func (g *githubStore) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	if g.token == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN tidak ditetapkan")
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// getFile memulangkan kandungan fail dan SHA-nya. found=false jika fail tiada (404).
+func (g *githubStore) getFile(path string) (content []byte, sha string, found bool, err error) {
+	req, err := g.newRequest("GET", g.contentsRefURL(path), nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, "", false, fmt.Errorf("Github API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var file struct {
+		SHA     string `json:"sha"`
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return nil, "", false, fmt.Errorf("gagal parse respons Github: %v", err)
+	}
+	// GitHub memecahkan base64 kepada beberapa baris
+	content, err = base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return nil, "", false, fmt.Errorf("gagal decode kandungan %s: %v", path, err)
+	}
+	return content, file.SHA, true, nil
+}
+
+// readFile membaca fail melalui indeks tempatan jika sedia, atau API contents jika tidak
+func (g *githubStore) readFile(path string) (content []byte, found bool, err error) {
+	sha, ok := g.index.lookup(path)
+	if !ok {
+		content, _, found, err = g.getFile(path)
+		return content, found, err
+	}
+	if sha == "" {
+		return nil, false, nil
+	}
+	content, err = g.getBlob(sha)
+	return content, err == nil, err
+}
+
+// putFile mencipta atau mengemaskini fail (SHA diambil dahulu jika fail sudah wujud)
+func (g *githubStore) putFile(path string, content []byte, message string) error {
+	sha, ok := g.index.lookup(path)
+	if !ok {
+		var err error
+		if _, sha, _, err = g.getFile(path); err != nil {
+			return err
+		}
+	}
+
+	payload := map[string]interface{}{
+		"message": message,
+		"content": base64.StdEncoding.EncodeToString(content),
+		"branch":  g.branch,
+	}
+	if sha != "" {
+		payload["sha"] = sha
+	}
+
+	body, _ := json.Marshal(payload)
+	req, err := g.newRequest("PUT", g.contentsURL(path), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Github API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// Kemaskini indeks terus supaya bacaan seterusnya tidak menunggu penyegaran berkala
+	var result struct {
+		Content struct {
+			SHA string `json:"sha"`
+		} `json:"content"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&result) == nil && result.Content.SHA != "" {
+		g.index.set(path, result.Content.SHA)
+	}
+	return nil
+}
+
+// deleteFile membuang fail (perlu SHA semasa). Fail yang sudah tiada dianggap berjaya.
+func (g *githubStore) deleteFile(path string, message string) error {
+	sha, ok := g.index.lookup(path)
+	if !ok {
+		var err error
+		if _, sha, _, err = g.getFile(path); err != nil {
+			return err
+		}
+	}
+	if sha == "" {
+		return nil
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"message": message,
+		"sha":     sha,
+		"branch":  g.branch,
+	})
+	req, err := g.newRequest("DELETE", g.contentsURL(path), bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Github API returned status %d: %s", resp.StatusCode, string(body))
+	}
+	g.index.set(path, "")
+	return nil
+}
+
+// listDir memulangkan laluan semua fail .json dalam satu folder
+func (g *githubStore) listDir(dir string) ([]string, error) {
+	if paths, ok := g.index.paths(dir); ok {
+		return paths, nil
+	}
+
+	req, err := g.newRequest("GET", g.contentsRefURL(dir), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Github API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var entries []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("gagal parse senarai %s: %v", dir, err)
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if e.Type == "file" && strings.HasSuffix(e.Path, ".json") {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths, nil
+}
+
+func (g *githubStore) GetAgreement(userID int64) (*AgreementRecord, error) {
+	content, found, err := g.readFile(fmt.Sprintf("agreements/%d.json", userID))
+	if err != nil || !found {
+		return nil, err
+	}
+	var rec AgreementRecord
+	if err := json.Unmarshal(content, &rec); err != nil {
+		return nil, fmt.Errorf("gagal parse rekod persetujuan %d: %v", userID, err)
+	}
+	return &rec, nil
+}
+
+func (g *githubStore) SaveAgreement(rec AgreementRecord) error {
+	jsonBytes, _ := json.MarshalIndent(rec, "", "  ")
+	return g.putFile(fmt.Sprintf("agreements/%d.json", rec.UserID), jsonBytes,
+		fmt.Sprintf("Audit Log: User %d has agreed to terms", rec.UserID))
+}
+
+func (g *githubStore) DeleteAgreement(userID int64) error {
+	return g.deleteFile(fmt.Sprintf("agreements/%d.json", userID),
+		fmt.Sprintf("Audit Log: User %d withdrew agreement", userID))
+}
+
+func (g *githubStore) ListAgreements() ([]AgreementRecord, error) {
+	paths, err := g.listDir("agreements")
+	if err != nil {
+		return nil, err
+	}
+	var recs []AgreementRecord
+	for _, p := range paths {
+		content, found, err := g.readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var rec AgreementRecord
+		if found && json.Unmarshal(content, &rec) == nil {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
+
+func (g *githubStore) GetBan(userID int64) (*BanRecord, error) {
+	content, found, err := g.readFile(fmt.Sprintf("blacklist/%d.json", userID))
+	if err != nil || !found {
+		return nil, err
+	}
+	var rec BanRecord
+	if err := json.Unmarshal(content, &rec); err != nil {
+		return nil, fmt.Errorf("gagal parse rekod sekatan %d: %v", userID, err)
+	}
+	return &rec, nil
+}
+
+func (g *githubStore) SaveBan(rec BanRecord) error {
+	jsonBytes, _ := json.MarshalIndent(rec, "", "  ")
+	return g.putFile(fmt.Sprintf("blacklist/%d.json", rec.UserID), jsonBytes,
+		fmt.Sprintf("Admin Action: Banning user %d by %s", rec.UserID, rec.ByAdmin))
+}
+
+func (g *githubStore) DeleteBan(userID int64) error {
+	return g.deleteFile(fmt.Sprintf("blacklist/%d.json", userID),
+		fmt.Sprintf("Admin Action: Unbanning user %d", userID))
+}
+
+func (g *githubStore) ListBans() ([]BanRecord, error) {
+	paths, err := g.listDir("blacklist")
+	if err != nil {
+		return nil, err
+	}
+	var recs []BanRecord
+	for _, p := range paths {
+		content, found, err := g.readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var rec BanRecord
+		if found && json.Unmarshal(content, &rec) == nil {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
+
+func (g *githubStore) GetStrikes(userID int64) (*StrikeRecord, error) {
+	content, found, err := g.readFile(fmt.Sprintf("strikes/%d.json", userID))
+	if err != nil || !found {
+		return nil, err
+	}
+	var rec StrikeRecord
+	if err := json.Unmarshal(content, &rec); err != nil {
+		return nil, fmt.Errorf("gagal parse rekod kesalahan spam %d: %v", userID, err)
+	}
+	return &rec, nil
+}
+
+func (g *githubStore) SaveStrikes(rec StrikeRecord) error {
+	jsonBytes, _ := json.MarshalIndent(rec, "", "  ")
+	return g.putFile(fmt.Sprintf("strikes/%d.json", rec.UserID), jsonBytes,
+		fmt.Sprintf("Anti-Spam: Strike history for user %d (%d events)", rec.UserID, len(rec.Events)))
+}
+
+func (g *githubStore) DeleteStrikes(userID int64) error {
+	return g.deleteFile(fmt.Sprintf("strikes/%d.json", userID),
+		fmt.Sprintf("Anti-Spam: Strike history for user %d expired", userID))
+}
+
+func (g *githubStore) ListStrikes() ([]StrikeRecord, error) {
+	paths, err := g.listDir("strikes")
+	if err != nil {
+		return nil, err
+	}
+	var recs []StrikeRecord
+	for _, p := range paths {
+		content, found, err := g.readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var rec StrikeRecord
+		if found && json.Unmarshal(content, &rec) == nil {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
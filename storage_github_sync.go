@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// githubIndex ialah salinan tempatan senarai fail agreements/, blacklist/ dan strikes/ (path -> blob SHA).
+// Diisi sekali semasa startup melalui Git Trees API, kemudian disegarkan dengan ETag.
+type githubIndex struct {
+	mu     sync.RWMutex
+	loaded bool
+	etag   string
+	files  map[string]string
+	// recent: tulisan tempatan terkini, supaya penyegaran yang bermula sebelum tulisan
+	// tidak memadamnya dari indeks
+	recent map[string]recentWrite
+	// blobs: kandungan blob mengikut SHA (blob Git tidak pernah berubah, jadi selamat di-cache).
+	// Hanya blob yang masih dirujuk oleh files disimpan; versi lama dibuang.
+	blobs map[string][]byte
+}
+
+type recentWrite struct {
+	sha string
+	at  time.Time
+}
+
+func newGithubIndex() *githubIndex {
+	return &githubIndex{
+		files:  make(map[string]string),
+		recent: make(map[string]recentWrite),
+		blobs:  make(map[string][]byte),
+	}
+}
+
+// lookup memulangkan SHA fail. ok=false bermaksud indeks belum sedia (guna API contents).
+func (ix *githubIndex) lookup(path string) (sha string, ok bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if !ix.loaded {
+		return "", false
+	}
+	return ix.files[path], true
+}
+
+// paths memulangkan semua fail dalam satu folder. ok=false jika indeks belum sedia.
+func (ix *githubIndex) paths(dir string) (paths []string, ok bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if !ix.loaded {
+		return nil, false
+	}
+	for p := range ix.files {
+		if strings.HasPrefix(p, dir+"/") {
+			paths = append(paths, p)
+		}
+	}
+	return paths, true
+}
+
+// set mengemaskini satu entri selepas tulisan berjaya (sha kosong = fail dibuang)
+func (ix *githubIndex) set(path, sha string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.recent[path] = recentWrite{sha: sha, at: time.Now()}
+	old := ix.files[path]
+	if sha == "" {
+		delete(ix.files, path)
+	} else {
+		ix.files[path] = sha
+	}
+	if old != "" && old != sha && !ix.referenced(old) {
+		delete(ix.blobs, old)
+	}
+}
+
+// referenced menyemak sama ada mana-mana fail masih menggunakan blob sha (dipanggil dengan mu dikunci)
+func (ix *githubIndex) referenced(sha string) bool {
+	for _, s := range ix.files {
+		if s == sha {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneBlobs membuang blob yang tidak lagi dirujuk oleh files (dipanggil dengan mu dikunci)
+func (ix *githubIndex) pruneBlobs() {
+	live := make(map[string]bool, len(ix.files))
+	for _, sha := range ix.files {
+		live[sha] = true
+	}
+	for sha := range ix.blobs {
+		if !live[sha] {
+			delete(ix.blobs, sha)
+		}
+	}
+}
+
+// trackedPath menentukan sama ada laluan dalam folder yang diindeks
+func trackedPath(path string) bool {
+	return strings.HasSuffix(path, ".json") &&
+		(strings.HasPrefix(path, "agreements/") || strings.HasPrefix(path, "blacklist/") ||
+			strings.HasPrefix(path, "strikes/"))
+}
+
+// syncIndex memuatkan seluruh pokok repo dalam satu panggilan.
+// Permintaan bersyarat (If-None-Match) memastikan 304 tidak memakan had kadar GitHub.
+func (g *githubStore) syncIndex() error {
+	url := fmt.Sprintf("%s/repos/%s/git/trees/%s?recursive=1", g.baseURL, g.repo, g.branch)
+	req, err := g.newRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+
+	g.index.mu.RLock()
+	etag := g.index.etag
+	g.index.mu.RUnlock()
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Github API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tree struct {
+		Tree []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+			SHA  string `json:"sha"`
+		} `json:"tree"`
+		Truncated bool `json:"truncated"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tree); err != nil {
+		return fmt.Errorf("gagal parse Git tree: %v", err)
+	}
+	if tree.Truncated {
+		// Senarai tidak lengkap: jangan percaya indeks, teruskan guna carian satu-satu
+		return fmt.Errorf("Git tree terlalu besar (truncated), indeks tidak digunakan")
+	}
+
+	files := make(map[string]string)
+	for _, e := range tree.Tree {
+		if e.Type == "blob" && trackedPath(e.Path) {
+			files[e.Path] = e.SHA
+		}
+	}
+
+	g.index.mu.Lock()
+	for path, w := range g.index.recent {
+		if w.at.Before(started) {
+			delete(g.index.recent, path)
+			continue
+		}
+		// Tulisan ini berlaku selepas permintaan dihantar; pokok mungkin belum mengandunginya
+		if w.sha == "" {
+			delete(files, path)
+		} else {
+			files[path] = w.sha
+		}
+	}
+	g.index.files = files
+	g.index.pruneBlobs()
+	g.index.etag = resp.Header.Get("ETag")
+	g.index.loaded = true
+	g.index.mu.Unlock()
+	return nil
+}
+
+// startSync menjalankan penyegaran indeks secara berkala di latar belakang
+func (g *githubStore) startSync(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := g.syncIndex(); err != nil {
+				log.Printf("⚠️ Gagal segarkan indeks GitHub: %v", err)
+			}
+		}
+	}()
+}
+
+// getBlob mengambil kandungan fail melalui SHA blob (di-cache selagi masih dirujuk indeks)
+func (g *githubStore) getBlob(sha string) ([]byte, error) {
+	g.index.mu.RLock()
+	content, ok := g.index.blobs[sha]
+	g.index.mu.RUnlock()
+	if ok {
+		return content, nil
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/git/blobs/%s", g.baseURL, g.repo, sha)
+	req, err := g.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Github API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var blob struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&blob); err != nil {
+		return nil, fmt.Errorf("gagal parse blob %s: %v", sha, err)
+	}
+	content, err = base64.StdEncoding.DecodeString(strings.ReplaceAll(blob.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("gagal decode blob %s: %v", sha, err)
+	}
+
+	g.index.mu.Lock()
+	// Fail mungkin telah ditulis semula semasa blob dimuat turun; jangan simpan versi lama
+	if g.index.referenced(sha) {
+		g.index.blobs[sha] = content
+	}
+	g.index.mu.Unlock()
+	return content, nil
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// githubStub ialah pelayan HTTP tempatan yang meniru bahagian GitHub API yang
+// digunakan oleh githubStore (Git trees, blobs dan contents)
+type githubStub struct {
+	mu sync.Mutex
+	// branch ialah cawangan yang digunakan oleh githubStore; files ialah kandungannya.
+	// defaultFiles ialah kandungan cawangan lalai repo ("main") jika branch berbeza.
+	branch       string
+	files        map[string][]byte
+	defaultFiles map[string][]byte
+	etag         string
+	truncated    bool
+	// duringTree dipanggil selepas pokok diambil tetapi sebelum respons dihantar
+	// (meniru tulisan yang berlaku semasa penyegaran sedang berjalan)
+	duringTree func()
+	calls      map[string]int
+}
+
+func newGithubStub(t *testing.T) (*githubStub, *githubStore) {
+	return newGithubStubOnBranch(t, "main")
+}
+
+func newGithubStubOnBranch(t *testing.T, branch string) (*githubStub, *githubStore) {
+	stub := &githubStub{
+		branch:       branch,
+		files:        make(map[string][]byte),
+		defaultFiles: make(map[string][]byte),
+		etag:         `"v1"`,
+		calls:        make(map[string]int),
+	}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	return stub, newGithubStore("token", "owner/repo", branch, srv.URL)
+}
+
+// filesOn memulangkan kandungan cawangan ref (kosong = cawangan lalai, dipanggil dengan mu dikunci)
+func (s *githubStub) filesOn(ref string) map[string][]byte {
+	if ref == "" {
+		ref = "main"
+	}
+	if ref == s.branch {
+		return s.files
+	}
+	return s.defaultFiles
+}
+
+func blobSHA(content []byte) string {
+	sum := sha1.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *githubStub) put(path string, v interface{}) {
+	data, _ := json.Marshal(v)
+	s.mu.Lock()
+	s.files[path] = data
+	s.mu.Unlock()
+}
+
+func (s *githubStub) count(kind string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[kind]
+}
+
+func (s *githubStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/repos/owner/repo/"
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	switch {
+	case strings.HasPrefix(path, "git/trees/"):
+		s.serveTree(w, r, strings.TrimPrefix(path, "git/trees/"))
+	case strings.HasPrefix(path, "git/blobs/"):
+		s.serveBlob(w, strings.TrimPrefix(path, "git/blobs/"))
+	case strings.HasPrefix(path, "contents/"):
+		s.serveContents(w, r, strings.TrimPrefix(path, "contents/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *githubStub) serveTree(w http.ResponseWriter, r *http.Request, ref string) {
+	s.mu.Lock()
+	s.calls["tree"]++
+	if r.Header.Get("If-None-Match") == s.etag {
+		s.calls["tree_304"]++
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	type entry struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	}
+	tree := struct {
+		Tree      []entry `json:"tree"`
+		Truncated bool    `json:"truncated"`
+	}{Tree: []entry{{Path: "README.md", Type: "blob", SHA: "readme"}}, Truncated: s.truncated}
+	for p, content := range s.filesOn(ref) {
+		tree.Tree = append(tree.Tree, entry{Path: p, Type: "blob", SHA: blobSHA(content)})
+	}
+	etag, during := s.etag, s.duringTree
+	s.mu.Unlock()
+
+	if during != nil {
+		during()
+	}
+	w.Header().Set("ETag", etag)
+	json.NewEncoder(w).Encode(tree)
+}
+
+func (s *githubStub) serveBlob(w http.ResponseWriter, sha string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["blob"]++
+	for _, files := range []map[string][]byte{s.files, s.defaultFiles} {
+		for _, content := range files {
+			if blobSHA(content) == sha {
+				json.NewEncoder(w).Encode(map[string]string{"content": base64.StdEncoding.EncodeToString(content)})
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func (s *githubStub) serveContents(w http.ResponseWriter, r *http.Request, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["contents_"+r.Method]++
+	switch r.Method {
+	case http.MethodGet:
+		files := s.filesOn(r.URL.Query().Get("ref"))
+		if content, ok := files[path]; ok {
+			json.NewEncoder(w).Encode(map[string]string{
+				"sha":     blobSHA(content),
+				"content": base64.StdEncoding.EncodeToString(content),
+			})
+			return
+		}
+		var entries []map[string]string
+		for p := range files {
+			if strings.HasPrefix(p, path+"/") {
+				entries = append(entries, map[string]string{"path": p, "type": "file"})
+			}
+		}
+		if entries == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(entries)
+	case http.MethodPut:
+		var payload struct {
+			Content string `json:"content"`
+			Branch  string `json:"branch"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		content, _ := base64.StdEncoding.DecodeString(payload.Content)
+		s.filesOn(payload.Branch)[path] = content
+		s.etag = fmt.Sprintf(`"v%d"`, s.calls["contents_PUT"]+1)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"content": map[string]string{"sha": blobSHA(content)}})
+	case http.MethodDelete:
+		var payload struct {
+			Branch string `json:"branch"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		delete(s.filesOn(payload.Branch), path)
+		s.etag = fmt.Sprintf(`"d%d"`, s.calls["contents_DELETE"])
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestGithubIndexInitialLoad(t *testing.T) {
+	stub, g := newGithubStub(t)
+	stub.put("agreements/1.json", AgreementRecord{UserID: 1, Status: "Agreed"})
+	stub.put("agreements/2.json", AgreementRecord{UserID: 2, Status: "Agreed"})
+	stub.put("blacklist/3.json", BanRecord{UserID: 3, Reason: "spam"})
+
+	if err := g.syncIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if stub.count("tree") != 1 {
+		t.Fatalf("pokok diminta %d kali, mahu 1", stub.count("tree"))
+	}
+
+	// Fail yang tiada dijawab terus dari indeks tanpa panggilan rangkaian
+	if rec, err := g.GetBan(1); err != nil || rec != nil {
+		t.Fatalf("GetBan(1) = %v, %v; mahu tiada", rec, err)
+	}
+	if rec, err := g.GetAgreement(1); err != nil || rec == nil || rec.UserID != 1 {
+		t.Fatalf("GetAgreement(1) = %v, %v", rec, err)
+	}
+	if rec, err := g.GetBan(3); err != nil || rec == nil || rec.Reason != "spam" {
+		t.Fatalf("GetBan(3) = %v, %v", rec, err)
+	}
+	// Blob di-cache: bacaan kedua tidak memanggil API lagi
+	blobs := stub.count("blob")
+	g.GetAgreement(1)
+	if stub.count("blob") != blobs {
+		t.Fatal("blob yang sama dimuat turun semula")
+	}
+
+	recs, err := g.ListAgreements()
+	if err != nil || len(recs) != 2 {
+		t.Fatalf("ListAgreements = %d rekod, %v; mahu 2", len(recs), err)
+	}
+	if n := stub.count("contents_GET"); n != 0 {
+		t.Fatalf("API contents dipanggil %d kali walaupun indeks sedia", n)
+	}
+}
+
+func TestGithubIndexNotModified(t *testing.T) {
+	stub, g := newGithubStub(t)
+	stub.put("agreements/1.json", AgreementRecord{UserID: 1})
+
+	if err := g.syncIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.syncIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if stub.count("tree") != 2 || stub.count("tree_304") != 1 {
+		t.Fatalf("tree=%d tree_304=%d; mahu permintaan kedua dijawab 304",
+			stub.count("tree"), stub.count("tree_304"))
+	}
+	// Indeks kekal selepas 304
+	if sha, ok := g.index.lookup("agreements/1.json"); !ok || sha == "" {
+		t.Fatal("indeks hilang selepas 304")
+	}
+
+	// Perubahan di repo (ETag baharu) dimuatkan pada penyegaran seterusnya
+	stub.put("blacklist/5.json", BanRecord{UserID: 5})
+	stub.mu.Lock()
+	stub.etag = `"v2"`
+	stub.mu.Unlock()
+	if err := g.syncIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := g.GetBan(5); rec == nil {
+		t.Fatal("sekatan baharu tidak dimuatkan selepas ETag berubah")
+	}
+}
+
+func TestGithubIndexTruncatedFallsBack(t *testing.T) {
+	stub, g := newGithubStub(t)
+	stub.put("agreements/1.json", AgreementRecord{UserID: 1})
+	stub.truncated = true
+
+	if err := g.syncIndex(); err == nil {
+		t.Fatal("pokok truncated sepatutnya memulangkan ralat")
+	}
+	if _, ok := g.index.lookup("agreements/1.json"); ok {
+		t.Fatal("indeks tidak sepatutnya digunakan selepas pokok truncated")
+	}
+
+	// Bacaan kembali kepada API contents satu-satu
+	if rec, err := g.GetAgreement(1); err != nil || rec == nil {
+		t.Fatalf("GetAgreement(1) = %v, %v", rec, err)
+	}
+	if rec, err := g.GetAgreement(2); err != nil || rec != nil {
+		t.Fatalf("GetAgreement(2) = %v, %v; mahu tiada", rec, err)
+	}
+	if n := stub.count("contents_GET"); n != 2 {
+		t.Fatalf("API contents dipanggil %d kali, mahu 2", n)
+	}
+	recs, err := g.ListAgreements()
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("ListAgreements = %d rekod, %v; mahu 1", len(recs), err)
+	}
+}
+
+func TestGithubIndexMergesRecentWrites(t *testing.T) {
+	stub, g := newGithubStub(t)
+	stub.put("agreements/1.json", AgreementRecord{UserID: 1})
+	if err := g.syncIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tulisan berlaku semasa penyegaran: pokok yang dipulangkan belum mengandunginya
+	stub.mu.Lock()
+	stub.etag = `"stale"`
+	stub.duringTree = func() {
+		if err := g.SaveAgreement(AgreementRecord{UserID: 2}); err != nil {
+			t.Error(err)
+		}
+		if err := g.DeleteAgreement(1); err != nil {
+			t.Error(err)
+		}
+	}
+	stub.mu.Unlock()
+	if err := g.syncIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec, _ := g.GetAgreement(2); rec == nil {
+		t.Fatal("tulisan terkini hilang selepas penyegaran dengan pokok lama")
+	}
+	if rec, _ := g.GetAgreement(1); rec != nil {
+		t.Fatal("fail yang dibuang muncul semula selepas penyegaran dengan pokok lama")
+	}
+
+	// Penyegaran seterusnya (bermula selepas tulisan) mempercayai pokok sepenuhnya
+	stub.mu.Lock()
+	stub.duringTree = nil
+	stub.etag = `"fresh"`
+	stub.mu.Unlock()
+	if err := g.syncIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.index.recent) != 0 {
+		t.Fatalf("tulisan terkini tidak dibersihkan: %v", g.index.recent)
+	}
+	paths, _ := g.listDir("agreements")
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != "agreements/2.json" {
+		t.Fatalf("agreements/ = %v", paths)
+	}
+}
+
+func TestGithubIndexDropsStaleBlobs(t *testing.T) {
+	stub, g := newGithubStub(t)
+	stub.put("strikes/1.json", StrikeRecord{UserID: 1})
+	if err := g.syncIndex(); err != nil {
+		t.Fatal(err)
+	}
+	g.GetStrikes(1)
+
+	// Setiap tulisan semula menggantikan blob lama dalam cache
+	for i := 0; i < 5; i++ {
+		rec := StrikeRecord{UserID: 1, Events: make([]StrikeEvent, i+1)}
+		if err := g.SaveStrikes(rec); err != nil {
+			t.Fatal(err)
+		}
+		g.GetStrikes(1)
+	}
+	if n := len(g.index.blobs); n != 1 {
+		t.Fatalf("%d blob dalam cache, mahu 1", n)
+	}
+
+	g.DeleteStrikes(1)
+	if n := len(g.index.blobs); n != 0 {
+		t.Fatalf("%d blob dalam cache selepas fail dibuang, mahu 0", n)
+	}
+
+	// Penyegaran membuang blob fail yang dipadam di luar bot
+	stub.put("strikes/2.json", StrikeRecord{UserID: 2})
+	stub.mu.Lock()
+	stub.etag = `"v9"`
+	stub.mu.Unlock()
+	g.syncIndex()
+	g.GetStrikes(2)
+	stub.mu.Lock()
+	delete(stub.files, "strikes/2.json")
+	stub.etag = `"v10"`
+	stub.mu.Unlock()
+	g.syncIndex()
+	if n := len(g.index.blobs); n != 0 {
+		t.Fatalf("%d blob dalam cache selepas penyegaran, mahu 0", n)
+	}
+}
+
+func TestGithubStoreUsesConfiguredBranch(t *testing.T) {
+	stub, g := newGithubStubOnBranch(t, "data")
+	stub.put("blacklist/1.json", BanRecord{UserID: 1, Reason: "spam"})
+	stub.defaultFiles["blacklist/2.json"], _ = json.Marshal(BanRecord{UserID: 2})
+
+	// Tanpa indeks: bacaan API contents mesti pada cawangan "data"
+	if rec, err := g.GetBan(1); err != nil || rec == nil {
+		t.Fatalf("GetBan(1) = %v, %v; mahu dibaca dari cawangan data", rec, err)
+	}
+	if rec, err := g.GetBan(2); err != nil || rec != nil {
+		t.Fatalf("GetBan(2) = %v, %v; fail cawangan lalai tidak sepatutnya dibaca", rec, err)
+	}
+	if bans, err := g.ListBans(); err != nil || len(bans) != 1 {
+		t.Fatalf("ListBans = %d rekod, %v; mahu 1", len(bans), err)
+	}
+
+	// Tulisan dan pemadaman juga pada cawangan "data"
+	if err := g.SaveAgreement(AgreementRecord{UserID: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.DeleteBan(1); err != nil {
+		t.Fatal(err)
+	}
+	stub.mu.Lock()
+	_, saved := stub.files["agreements/3.json"]
+	_, leaked := stub.defaultFiles["agreements/3.json"]
+	_, stillBanned := stub.files["blacklist/1.json"]
+	stub.mu.Unlock()
+	if !saved || leaked {
+		t.Fatalf("persetujuan ditulis ke cawangan salah (data=%t, main=%t)", saved, leaked)
+	}
+	if stillBanned {
+		t.Fatal("sekatan tidak dipadam dari cawangan data")
+	}
+
+	// Indeks dibina dari pokok cawangan yang sama
+	if err := g.syncIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := g.GetAgreement(3); rec == nil {
+		t.Fatal("indeks tidak mengandungi fail cawangan data")
+	}
+	if rec, _ := g.GetBan(2); rec != nil {
+		t.Fatal("indeks mengandungi fail cawangan lalai")
+	}
+}
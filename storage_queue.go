@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Jenis operasi tulisan dalam jurnal
+const (
+	opSaveAgreement   = "save_agreement"
+	opDeleteAgreement = "delete_agreement"
+	opSaveBan         = "save_ban"
+	opDeleteBan       = "delete_ban"
+	opSaveStrikes     = "save_strikes"
+	opDeleteStrikes   = "delete_strikes"
+)
+
+// journalOp ialah satu baris dalam jurnal tulisan. Baris dengan Done=true menandakan
+// operasi ID tersebut telah selesai (berjaya atau gagal kekal) dan boleh diabaikan.
+type journalOp struct {
+	ID        uint64           `json:"id"`
+	Kind      string           `json:"kind,omitempty"`
+	UserID    int64            `json:"user_id,omitempty"`
+	Agreement *AgreementRecord `json:"agreement,omitempty"`
+	Ban       *BanRecord       `json:"ban,omitempty"`
+	Strikes   *StrikeRecord    `json:"strikes,omitempty"`
+	CreatedAt time.Time        `json:"created_at,omitempty"`
+	Done      bool             `json:"done,omitempty"`
+}
+
+// userID memulangkan ID user yang terlibat dalam operasi
+func (op journalOp) userID() int64 {
+	switch {
+	case op.Agreement != nil:
+		return op.Agreement.UserID
+	case op.Ban != nil:
+		return op.Ban.UserID
+	case op.Strikes != nil:
+		return op.Strikes.UserID
+	}
+	return op.UserID
+}
+
+// writeQueue menulis ke jurnal di cakera dahulu, kemudian pekerja latar belakang
+// memainkan semula jurnal ke backend sebenar dengan exponential backoff.
+// Bacaan melihat tulisan yang masih tertunda supaya user tidak perlu menunggu.
+type writeQueue struct {
+	backend     Store
+	path        string
+	maxAttempts int
+	// onFailure dipanggil apabila operasi gagal secara kekal selepas semua cubaan
+	onFailure func(op journalOp, err error)
+
+	mu      sync.Mutex
+	file    *os.File
+	nextID  uint64
+	pending []journalOp
+	wake    chan struct{}
+}
+
+func newWriteQueue(backend Store, path string, maxAttempts int) (*writeQueue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("gagal cipta folder jurnal: %v", err)
+	}
+	q := &writeQueue{
+		backend:     backend,
+		path:        path,
+		maxAttempts: maxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// replay membaca jurnal sedia ada, mengekalkan operasi yang belum selesai dan
+// menulis semula fail supaya hanya mengandungi operasi tersebut
+func (q *writeQueue) replay() error {
+	done := make(map[uint64]bool)
+	var ops []journalOp
+
+	if f, err := os.Open(q.path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var op journalOp
+			if json.Unmarshal(scanner.Bytes(), &op) != nil {
+				continue // baris separuh ditulis semasa crash
+			}
+			if op.ID >= q.nextID {
+				q.nextID = op.ID + 1
+			}
+			if op.Done {
+				done[op.ID] = true
+			} else {
+				ops = append(ops, op)
+			}
+		}
+		f.Close()
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("gagal buka jurnal %s: %v", q.path, err)
+	}
+
+	for _, op := range ops {
+		if !done[op.ID] {
+			q.pending = append(q.pending, op)
+		}
+	}
+	if len(q.pending) > 0 {
+		log.Printf("📒 %d tulisan tertunda dimuatkan dari jurnal", len(q.pending))
+	}
+	return q.rewrite()
+}
+
+// rewrite menulis semula jurnal dengan operasi tertunda sahaja (dipanggil dengan mu dikunci atau semasa init).
+// Fail lama kekal terbuka sehingga fail baharu menggantikannya, jadi kegagalan di
+// mana-mana langkah tidak menghalang tulisan seterusnya.
+func (q *writeQueue) rewrite() error {
+	tmp := q.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("gagal tulis jurnal: %v", err)
+	}
+	enc := json.NewEncoder(f)
+	for _, op := range q.pending {
+		enc.Encode(op)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	f.Close()
+	if err := os.Rename(tmp, q.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// Fail lama kini telah diganti; tulisan seterusnya mesti ke fail baharu.
+	// Jika gagal dibuka, q.file kekal nil dan appendLine akan cuba buka semula.
+	if q.file != nil {
+		q.file.Close()
+	}
+	q.file, err = os.OpenFile(q.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	return err
+}
+
+// appendLine menulis satu baris ke jurnal dan fsync (dipanggil dengan mu dikunci)
+func (q *writeQueue) appendLine(op journalOp) error {
+	if q.file == nil {
+		f, err := os.OpenFile(q.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		q.file = f
+	}
+	data, _ := json.Marshal(op)
+	if _, err := q.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return q.file.Sync()
+}
+
+// enqueue menyimpan operasi ke jurnal dan mengejutkan pekerja
+func (q *writeQueue) enqueue(op journalOp) error {
+	q.mu.Lock()
+	op.ID = q.nextID
+	op.CreatedAt = time.Now()
+	if err := q.appendLine(op); err != nil {
+		q.mu.Unlock()
+		return fmt.Errorf("gagal tulis ke jurnal: %v", err)
+	}
+	q.nextID++
+	if i := q.coalesceIndex(op); i > 0 {
+		// Buang tulisan lama (susunan kekal sama seperti jurnal) dan tanda selesai
+		// supaya tidak dimainkan semula
+		replaced := q.pending[i]
+		q.pending = append(q.pending[:i], q.pending[i+1:]...)
+		if err := q.appendLine(journalOp{ID: replaced.ID, Done: true}); err != nil {
+			log.Printf("⚠️ Gagal tanda operasi %d digantikan dalam jurnal: %v", replaced.ID, err)
+		}
+	}
+	q.pending = append(q.pending, op)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// coalesceIndex memulangkan kedudukan tulisan sejarah kesalahan spam tertunda bagi user
+// yang sama, atau -1. Hanya keadaan terakhir perlu sampai ke backend, jadi semasa barisan
+// tersekat (contoh had kadar GitHub semasa gelombang spam) setiap user memegang paling
+// banyak satu tulisan yang menunggu. Operasi pertama tidak pernah diganti kerana mungkin
+// sedang dijalankan oleh pekerja (dipanggil dengan mu dikunci).
+func (q *writeQueue) coalesceIndex(op journalOp) int {
+	if op.Kind != opSaveStrikes && op.Kind != opDeleteStrikes {
+		return -1
+	}
+	for i := len(q.pending) - 1; i > 0; i-- {
+		p := q.pending[i]
+		if (p.Kind == opSaveStrikes || p.Kind == opDeleteStrikes) && p.userID() == op.userID() {
+			return i
+		}
+	}
+	return -1
+}
+
+// finish menandakan operasi pertama dalam barisan sebagai selesai
+func (q *writeQueue) finish(op journalOp) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = q.pending[1:]
+	if len(q.pending) == 0 {
+		// Jurnal kosong: padatkan fail supaya tidak membesar tanpa had
+		err := q.rewrite()
+		if err == nil {
+			return
+		}
+		// Jurnal lama masih digunakan: tanda selesai supaya tidak dimainkan semula
+		log.Printf("⚠️ Gagal padatkan jurnal: %v", err)
+	}
+	if err := q.appendLine(journalOp{ID: op.ID, Done: true}); err != nil {
+		log.Printf("⚠️ Gagal tanda operasi %d selesai dalam jurnal: %v", op.ID, err)
+	}
+}
+
+// apply menjalankan satu operasi ke backend sebenar
+func (q *writeQueue) apply(op journalOp) error {
+	switch op.Kind {
+	case opSaveAgreement:
+		return q.backend.SaveAgreement(*op.Agreement)
+	case opDeleteAgreement:
+		return q.backend.DeleteAgreement(op.UserID)
+	case opSaveBan:
+		return q.backend.SaveBan(*op.Ban)
+	case opDeleteBan:
+		return q.backend.DeleteBan(op.UserID)
+	case opSaveStrikes:
+		return q.backend.SaveStrikes(*op.Strikes)
+	case opDeleteStrikes:
+		return q.backend.DeleteStrikes(op.UserID)
+	}
+	return fmt.Errorf("jenis operasi tidak dikenali: %q", op.Kind)
+}
+
+// start menjalankan pekerja latar belakang. Operasi diproses mengikut turutan (FIFO)
+// supaya tulisan untuk user yang sama tidak bertukar susunan.
+func (q *writeQueue) start() {
+	go func() {
+		attempts := 0
+		for {
+			q.mu.Lock()
+			if len(q.pending) == 0 {
+				q.mu.Unlock()
+				<-q.wake
+				continue
+			}
+			op := q.pending[0]
+			q.mu.Unlock()
+
+			err := q.apply(op)
+			if err == nil {
+				attempts = 0
+				q.finish(op)
+				continue
+			}
+
+			attempts++
+			if attempts >= q.maxAttempts {
+				log.Printf("❌ Tulisan %s untuk user %d gagal secara kekal: %v", op.Kind, op.userID(), err)
+				attempts = 0
+				q.finish(op)
+				if q.onFailure != nil {
+					q.onFailure(op, err)
+				}
+				continue
+			}
+
+			delay := backoffDelay(attempts)
+			log.Printf("⚠️ Tulisan %s untuk user %d gagal (cubaan %d/%d), cuba semula dalam %s: %v",
+				op.Kind, op.userID(), attempts, q.maxAttempts, delay, err)
+			time.Sleep(delay)
+		}
+	}()
+}
+
+// backoffDelay: 1s, 2s, 4s, ... dihadkan kepada 5 minit
+func backoffDelay(attempt int) time.Duration {
+	d := time.Second << uint(attempt-1)
+	if d <= 0 || d > 5*time.Minute {
+		return 5 * time.Minute
+	}
+	return d
+}
+
+// Pending memulangkan bilangan tulisan yang belum sampai ke backend
+func (q *writeQueue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// latest memulangkan operasi tertunda terakhir yang sepadan dengan syarat
+func (q *writeQueue) latest(match func(op journalOp) bool) (journalOp, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := len(q.pending) - 1; i >= 0; i-- {
+		if match(q.pending[i]) {
+			return q.pending[i], true
+		}
+	}
+	return journalOp{}, false
+}
+
+// mergePending menggabungkan tulisan tertunda dalam jurnal ke atas senarai dari backend:
+// rekod yang disimpan menggantikan (atau ditambah kepada) senarai, rekod yang dipadam dibuang
+func mergePending[T any](q *writeQueue, recs []T, saveKind, deleteKind string,
+	recOf func(journalOp) T, userOf func(T) int64) []T {
+	seen := make(map[int64]int)
+	for i, r := range recs {
+		seen[userOf(r)] = i
+	}
+	deleted := make(map[int64]bool)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, op := range q.pending {
+		switch op.Kind {
+		case deleteKind:
+			deleted[op.UserID] = true
+		case saveKind:
+			rec := recOf(op)
+			id := userOf(rec)
+			delete(deleted, id)
+			if i, ok := seen[id]; ok {
+				recs[i] = rec
+			} else {
+				seen[id] = len(recs)
+				recs = append(recs, rec)
+			}
+		}
+	}
+	if len(deleted) == 0 {
+		return recs
+	}
+	kept := recs[:0]
+	for _, r := range recs {
+		if !deleted[userOf(r)] {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func (q *writeQueue) GetAgreement(userID int64) (*AgreementRecord, error) {
+	if op, ok := q.latest(func(op journalOp) bool {
+		return (op.Kind == opSaveAgreement || op.Kind == opDeleteAgreement) && op.userID() == userID
+	}); ok {
+		if op.Kind == opDeleteAgreement {
+			return nil, nil
+		}
+		rec := *op.Agreement
+		return &rec, nil
+	}
+	return q.backend.GetAgreement(userID)
+}
+
+func (q *writeQueue) SaveAgreement(rec AgreementRecord) error {
+	return q.enqueue(journalOp{Kind: opSaveAgreement, Agreement: &rec})
+}
+
+func (q *writeQueue) DeleteAgreement(userID int64) error {
+	return q.enqueue(journalOp{Kind: opDeleteAgreement, UserID: userID})
+}
+
+func (q *writeQueue) ListAgreements() ([]AgreementRecord, error) {
+	recs, err := q.backend.ListAgreements()
+	if err != nil {
+		return nil, err
+	}
+	return mergePending(q, recs, opSaveAgreement, opDeleteAgreement,
+		func(op journalOp) AgreementRecord { return *op.Agreement },
+		func(r AgreementRecord) int64 { return r.UserID }), nil
+}
+
+func (q *writeQueue) GetBan(userID int64) (*BanRecord, error) {
+	if op, ok := q.latest(func(op journalOp) bool {
+		return (op.Kind == opSaveBan || op.Kind == opDeleteBan) && op.userID() == userID
+	}); ok {
+		if op.Kind == opDeleteBan {
+			return nil, nil
+		}
+		rec := *op.Ban
+		return &rec, nil
+	}
+	return q.backend.GetBan(userID)
+}
+
+func (q *writeQueue) SaveBan(rec BanRecord) error {
+	return q.enqueue(journalOp{Kind: opSaveBan, Ban: &rec})
+}
+
+func (q *writeQueue) DeleteBan(userID int64) error {
+	return q.enqueue(journalOp{Kind: opDeleteBan, UserID: userID})
+}
+
+func (q *writeQueue) ListBans() ([]BanRecord, error) {
+	recs, err := q.backend.ListBans()
+	if err != nil {
+		return nil, err
+	}
+	return mergePending(q, recs, opSaveBan, opDeleteBan,
+		func(op journalOp) BanRecord { return *op.Ban },
+		func(r BanRecord) int64 { return r.UserID }), nil
+}
+
+func (q *writeQueue) GetStrikes(userID int64) (*StrikeRecord, error) {
+	if op, ok := q.latest(func(op journalOp) bool {
+		return (op.Kind == opSaveStrikes || op.Kind == opDeleteStrikes) && op.userID() == userID
+	}); ok {
+		if op.Kind == opDeleteStrikes {
+			return nil, nil
+		}
+		rec := *op.Strikes
+		return &rec, nil
+	}
+	return q.backend.GetStrikes(userID)
+}
+
+func (q *writeQueue) SaveStrikes(rec StrikeRecord) error {
+	return q.enqueue(journalOp{Kind: opSaveStrikes, Strikes: &rec})
+}
+
+func (q *writeQueue) DeleteStrikes(userID int64) error {
+	return q.enqueue(journalOp{Kind: opDeleteStrikes, UserID: userID})
+}
+
+func (q *writeQueue) ListStrikes() ([]StrikeRecord, error) {
+	recs, err := q.backend.ListStrikes()
+	if err != nil {
+		return nil, err
+	}
+	return mergePending(q, recs, opSaveStrikes, opDeleteStrikes,
+		func(op journalOp) StrikeRecord { return *op.Strikes },
+		func(r StrikeRecord) int64 { return r.UserID }), nil
+}
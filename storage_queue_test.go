@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteQueueCoalescesStrikeWrites(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := newFSStore(filepath.Join(dir, "data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal := filepath.Join(dir, "journal.jsonl")
+	q, err := newWriteQueue(backend, journal, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strikes := func(userID int64, n int) StrikeRecord {
+		rec := StrikeRecord{UserID: userID}
+		for i := 0; i < n; i++ {
+			rec.Events = append(rec.Events, StrikeEvent{At: time.Unix(int64(i), 0), Action: ActionText})
+		}
+		return rec
+	}
+	// Pekerja tidak dijalankan: operasi pertama dianggap sedang dijalankan dan tidak diganti
+	for n := 1; n <= 4; n++ {
+		if err := q.SaveStrikes(strikes(1, n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.SaveBan(BanRecord{UserID: 2, Reason: "spam", BannedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SaveStrikes(strikes(1, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.DeleteStrikes(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// strikes(1,1) sedang berjalan, ban 2, strikes(1,5) menggantikan 2..4, padam strikes 2
+	if got := q.Pending(); got != 4 {
+		t.Fatalf("Pending = %d, mahu 4", got)
+	}
+	rec, err := q.GetStrikes(1)
+	if err != nil || rec == nil || len(rec.Events) != 5 {
+		t.Fatalf("GetStrikes(1) = %+v, %v; mahu 5 kesalahan", rec, err)
+	}
+
+	// Jurnal dimainkan semula tanpa tulisan yang telah digantikan
+	q.file.Close()
+	replayed, err := newWriteQueue(backend, journal, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed.Pending(); got != 4 {
+		t.Fatalf("Pending selepas replay = %d, mahu 4", got)
+	}
+	kinds := []string{opSaveStrikes, opSaveBan, opSaveStrikes, opDeleteStrikes}
+	for i, op := range replayed.pending {
+		if op.Kind != kinds[i] {
+			t.Fatalf("operasi %d = %s, mahu %s", i, op.Kind, kinds[i])
+		}
+	}
+	if n := len(replayed.pending[2].Strikes.Events); n != 5 {
+		t.Fatalf("operasi digabung mempunyai %d kesalahan, mahu 5", n)
+	}
+
+	// Pekerja menulis keadaan terakhir ke backend
+	replayed.start()
+	deadline := time.Now().Add(2 * time.Second)
+	for replayed.Pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got, err := backend.GetStrikes(1)
+	if err != nil || got == nil || len(got.Events) != 5 {
+		t.Fatalf("backend.GetStrikes(1) = %+v, %v; mahu 5 kesalahan", got, err)
+	}
+}
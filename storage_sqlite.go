@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteStore menyimpan rekod dalam pangkalan data SQLite terbenam (tanpa CGO)
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("gagal buka SQLite %s: %v", path, err)
+	}
+	// SQLite hanya benarkan satu penulis pada satu masa
+	db.SetMaxOpenConns(1)
+
+	schema := `
+CREATE TABLE IF NOT EXISTS agreements (user_id INTEGER PRIMARY KEY, data TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS bans (user_id INTEGER PRIMARY KEY, data TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS strikes (user_id INTEGER PRIMARY KEY, data TEXT NOT NULL);`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("gagal cipta skema SQLite: %v", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+// getJSON membaca satu baris dan decode lajur data ke dalam v
+func (s *sqliteStore) getJSON(table string, userID int64, v interface{}) (found bool, err error) {
+	var data string
+	err = s.db.QueryRow("SELECT data FROM "+table+" WHERE user_id = ?", userID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		return false, fmt.Errorf("gagal parse rekod %s %d: %v", table, userID, err)
+	}
+	return true, nil
+}
+
+func (s *sqliteStore) putJSON(table string, userID int64, v interface{}) error {
+	data, _ := json.Marshal(v)
+	_, err := s.db.Exec("INSERT INTO "+table+" (user_id, data) VALUES (?, ?) "+
+		"ON CONFLICT(user_id) DO UPDATE SET data = excluded.data", userID, string(data))
+	return err
+}
+
+func (s *sqliteStore) deleteRow(table string, userID int64) error {
+	_, err := s.db.Exec("DELETE FROM "+table+" WHERE user_id = ?", userID)
+	return err
+}
+
+// listJSON memanggil fn untuk lajur data setiap baris dalam jadual
+func (s *sqliteStore) listJSON(table string, fn func(data []byte)) error {
+	rows, err := s.db.Query("SELECT data FROM " + table + " ORDER BY user_id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		fn([]byte(data))
+	}
+	return rows.Err()
+}
+
+func (s *sqliteStore) GetAgreement(userID int64) (*AgreementRecord, error) {
+	var rec AgreementRecord
+	found, err := s.getJSON("agreements", userID, &rec)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *sqliteStore) SaveAgreement(rec AgreementRecord) error {
+	return s.putJSON("agreements", rec.UserID, rec)
+}
+
+func (s *sqliteStore) DeleteAgreement(userID int64) error {
+	return s.deleteRow("agreements", userID)
+}
+
+func (s *sqliteStore) ListAgreements() ([]AgreementRecord, error) {
+	var recs []AgreementRecord
+	err := s.listJSON("agreements", func(data []byte) {
+		var rec AgreementRecord
+		if json.Unmarshal(data, &rec) == nil {
+			recs = append(recs, rec)
+		}
+	})
+	return recs, err
+}
+
+func (s *sqliteStore) GetBan(userID int64) (*BanRecord, error) {
+	var rec BanRecord
+	found, err := s.getJSON("bans", userID, &rec)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *sqliteStore) SaveBan(rec BanRecord) error {
+	return s.putJSON("bans", rec.UserID, rec)
+}
+
+func (s *sqliteStore) DeleteBan(userID int64) error {
+	return s.deleteRow("bans", userID)
+}
+
+func (s *sqliteStore) ListBans() ([]BanRecord, error) {
+	var recs []BanRecord
+	err := s.listJSON("bans", func(data []byte) {
+		var rec BanRecord
+		if json.Unmarshal(data, &rec) == nil {
+			recs = append(recs, rec)
+		}
+	})
+	return recs, err
+}
+
+func (s *sqliteStore) GetStrikes(userID int64) (*StrikeRecord, error) {
+	var rec StrikeRecord
+	found, err := s.getJSON("strikes", userID, &rec)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *sqliteStore) SaveStrikes(rec StrikeRecord) error {
+	return s.putJSON("strikes", rec.UserID, rec)
+}
+
+func (s *sqliteStore) DeleteStrikes(userID int64) error {
+	return s.deleteRow("strikes", userID)
+}
+
+func (s *sqliteStore) ListStrikes() ([]StrikeRecord, error) {
+	var recs []StrikeRecord
+	err := s.listJSON("strikes", func(data []byte) {
+		var rec StrikeRecord
+		if json.Unmarshal(data, &rec) == nil {
+			recs = append(recs, rec)
+		}
+	})
+	return recs, err
+}
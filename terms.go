@@ -1,30 +1,24 @@
 package main
 
 import (
-	"bytes"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"os"
-	"strings"
 	"time"
 )
 
-// Konfigurasi Utama
-const ADMIN_ID int64 = 7348614053 
-
-var (
-	githubToken = os.Getenv("GITHUB_TOKEN")
-	githubRepo  = "Lilmoki91/CRYPTORIAN-TELEBOT"
-	termsURL    = "https://raw.githubusercontent.com/Lilmoki91/CRYPTORIAN-TELEBOT/main/terms.json"
-)
+var termsURL = "https://raw.githubusercontent.com/Lilmoki91/CRYPTORIAN-TELEBOT/main/terms.json"
 
 type TermsData struct {
-	ProjectName        string `json:"project_name"`
+	ProjectName string `json:"project_name"`
+	Version     string `json:"version"`
+	LastUpdated string `json:"last_updated"`
+	// Changes ialah ringkasan perubahan bagi setiap versi (untuk persetujuan semula)
+	Changes            []TermsChange `json:"changes"`
 	TermsAndConditions struct {
 		Title    string `json:"title"`
+		Intro    string `json:"intro"`
 		Sections []struct {
 			ID      int      `json:"id"`
 			Heading string   `json:"heading"`
@@ -35,224 +29,142 @@ type TermsData struct {
 	} `json:"terms_and_conditions"`
 }
 
-// IsAdmin menyemak jika pengguna adalah Mr JOHAN
-func IsAdmin(userID int64) bool {
-	return userID == ADMIN_ID
-}
-
-// IsBanned menyemak jika ID user wujud dalam folder blacklist/ di GitHub
-func IsBanned(userID int64) bool {
-	if IsAdmin(userID) {
-		return false // Admin tidak boleh di-ban
+// IsBanned menyemak jika ID user mempunyai rekod sekatan dalam storan.
+// Ralat storan dipulangkan kepada pemanggil; lihat BannedWithPolicy untuk keputusan akhir.
+func IsBanned(userID int64) (bool, error) {
+	if HasPermission(userID, PermBypassChecks) {
+		return false, nil // Pasukan moderasi tidak boleh di-ban
 	}
 
-	if githubToken == "" {
-		return false
-	}
-
-	url := fmt.Sprintf("https://api.github.com/repos/%s/contents/blacklist/%d.json", githubRepo, userID)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+githubToken)
-
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
+	rec, err := store.GetBan(userID)
 	if err != nil {
-		return false
+		return false, err
 	}
-	defer resp.Body.Close()
-
-	return resp.StatusCode == http.StatusOK
+	// Sekatan sementara yang telah tamat (atau ditarik balik melalui rayuan) tidak dikira
+	return rec != nil && rec.Active(time.Now()), nil
 }
 
-// HasAgreed menyemak jika fail ID user wujud di GitHub (Admin automatik lepas)
-func HasAgreed(userID int64) bool {
-	if IsAdmin(userID) {
-		return true // Mr JOHAN tak perlu klik setuju
-	}
-
-	if githubToken == "" {
-		return false
+// HasAgreed menyemak jika rekod persetujuan user wujud dalam storan (Admin automatik lepas).
+// Ralat storan dipulangkan kepada pemanggil; lihat AgreedWithPolicy untuk keputusan akhir.
+func HasAgreed(userID int64) (bool, error) {
+	if HasPermission(userID, PermBypassChecks) {
+		return true, nil // Pasukan moderasi tak perlu klik setuju
 	}
 
-	url := fmt.Sprintf("https://api.github.com/repos/%s/contents/agreements/%d.json", githubRepo, userID)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+githubToken)
-	req.Header.Set("Accept", "application/vnd.github+json")
-
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
+	rec, err := store.GetAgreement(userID)
 	if err != nil {
-		return false
+		return false, err
 	}
-	defer resp.Body.Close()
-
-	return resp.StatusCode == http.StatusOK
+	// Persetujuan untuk versi terma yang lama perlu diperbaharui
+	return rec != nil && !termsOutdated(rec.TermsVersion), nil
 }
 
-// BuildTermsUI mengambil JSON dan menukarnya menjadi teks Markdown Standard (Tanpa V2 Escape)
-func BuildTermsUI() (string, error) {
-	resp, err := http.Get(termsURL)
+// fetchTerms memuat turun terms.json secara bersyarat (ETag / Last-Modified) dan
+// menerbitkannya jika berubah. Memulangkan terma semasa dalam memori.
+func fetchTerms() (*TermsData, error) {
+	req, err := http.NewRequest("GET", termsURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("gagal akses URL Terma: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	var data TermsData
-	if err := json.Unmarshal(body, &data); err != nil {
-		return "", fmt.Errorf("gagal parse JSON: %v", err)
-	}
-
-	var sb strings.Builder
-
-	// Header
-	// Menggunakan format **Teks** untuk bold dalam Markdown Standard
-	sb.WriteString(fmt.Sprintf("**%s**\n\n", data.TermsAndConditions.Title))
-	sb.WriteString("Sila baca dan patuhi terma dan syarat berikut:\n\n")
-
-	// Sections
-	for _, sec := range data.TermsAndConditions.Sections {
-		// Menggunakan titik biasa ".", bukan "\."
-		sb.WriteString(fmt.Sprintf("%d. **%s**\n", sec.ID, sec.Heading))
-		for _, line := range sec.Content {
-			sb.WriteString(fmt.Sprintf("• %s\n", line))
-		}
-		sb.WriteString("\n")
+	termsRemote.Lock()
+	if termsRemote.etag != "" {
+		req.Header.Set("If-None-Match", termsRemote.etag)
 	}
-
-	// Footer
-	// Menggunakan garisan visual biasa
-	sb.WriteString("───────────────────────\n\n")
-	// Menggunakan _Teks_ untuk italic dalam Markdown Standard
-	sb.WriteString("_Untuk teruskan sesi operasi bot sila pilih:_")
-
-	return sb.String(), nil
-}
-
-// SaveAgreementToGithub menyimpan fail JSON baru ke repo (Audit Log)
-func SaveAgreementToGithub(userID int64, username string) error {
-	if githubToken == "" {
-		return fmt.Errorf("GITHUB_TOKEN tidak ditetapkan")
+	if termsRemote.lastModified != "" {
+		req.Header.Set("If-Modified-Since", termsRemote.lastModified)
 	}
+	termsRemote.Unlock()
 
-	url := fmt.Sprintf("https://api.github.com/repos/%s/contents/agreements/%d.json", githubRepo, userID)
-
-	logData := map[string]interface{}{
-		"user_id":   userID,
-		"username":  username,
-		"agreed_at": time.Now().Format(time.RFC3339),
-		"status":    "AGREED",
+	resp, err := termsClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gagal akses URL Terma: %v", err)
 	}
+	defer resp.Body.Close()
 
-	jsonBytes, _ := json.MarshalIndent(logData, "", "  ")
-
-	// Check if file already exists to get SHA (untuk update)
-	var sha string
-	existingURL := fmt.Sprintf("https://api.github.com/repos/%s/contents/agreements/%d.json", githubRepo, userID)
-	req, _ := http.NewRequest("GET", existingURL, nil)
-	req.Header.Set("Authorization", "Bearer "+githubToken)
-	req.Header.Set("Accept", "application/vnd.github+json")
-
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err == nil && resp.StatusCode == http.StatusOK {
-		defer resp.Body.Close()
-		var existingData map[string]interface{}
-		body, _ := io.ReadAll(resp.Body)
-		json.Unmarshal(body, &existingData)
-		if s, ok := existingData["sha"].(string); ok {
-			sha = s
+	if resp.StatusCode == http.StatusNotModified {
+		if data, _ := publishedTerms(); data != nil {
+			return data, nil
 		}
+		return nil, fmt.Errorf("terma tidak berubah tetapi tiada salinan dalam memori")
 	}
-
-	payload := map[string]interface{}{
-		"message": fmt.Sprintf("Audit Log: User %d has agreed to terms", userID),
-		"content": base64.StdEncoding.EncodeToString(jsonBytes),
-	}
-
-	if sha != "" {
-		payload["sha"] = sha
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("URL Terma memulangkan status %d", resp.StatusCode)
 	}
 
-	body, _ := json.Marshal(payload)
-	req, _ = http.NewRequest("PUT", url, bytes.NewBuffer(body))
-	req.Header.Set("Authorization", "Bearer "+githubToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err = client.Do(req)
+	body, _ := io.ReadAll(resp.Body)
+	// Tolak terma yang diubah tanpa tandatangan sah (guna salinan sah terakhir)
+	body, err = verifiedTermsBody(body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("Github API returned status %d: %s", resp.StatusCode, string(body))
+	data, err := publishTermsBody(body)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	termsRemote.Lock()
+	termsRemote.etag = resp.Header.Get("ETag")
+	termsRemote.lastModified = resp.Header.Get("Last-Modified")
+	termsRemote.Unlock()
+	return data, nil
 }
 
-// BanUser digunakan oleh Admin untuk sekat user secara automatik ke GitHub
-func BanUser(userID int64, reason string) error {
-	if githubToken == "" {
-		return fmt.Errorf("GITHUB_TOKEN tidak ditetapkan")
-	}
-
-	url := fmt.Sprintf("https://api.github.com/repos/%s/contents/blacklist/%d.json", githubRepo, userID)
-
-	logData := map[string]interface{}{
-		"user_id":   userID,
-		"reason":    reason,
-		"banned_at": time.Now().Format(time.RFC3339),
-		"by_admin":  "Mr JOHAN",
-	}
-
-	jsonBytes, _ := json.MarshalIndent(logData, "", "  ")
-
-	// Check if file already exists to get SHA
-	var sha string
-	existingURL := fmt.Sprintf("https://api.github.com/repos/%s/contents/blacklist/%d.json", githubRepo, userID)
-	req, _ := http.NewRequest("GET", existingURL, nil)
-	req.Header.Set("Authorization", "Bearer "+githubToken)
-	req.Header.Set("Accept", "application/vnd.github+json")
-
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err == nil && resp.StatusCode == http.StatusOK {
-		defer resp.Body.Close()
-		var existingData map[string]interface{}
-		body, _ := io.ReadAll(resp.Body)
-		json.Unmarshal(body, &existingData)
-		if s, ok := existingData["sha"].(string); ok {
-			sha = s
-		}
-	}
-
-	payload := map[string]interface{}{
-		"message": fmt.Sprintf("Admin Action: Banning user %d", userID),
-		"content": base64.StdEncoding.EncodeToString(jsonBytes),
-	}
-
-	if sha != "" {
-		payload["sha"] = sha
-	}
-
-	body, _ := json.Marshal(payload)
-	req, _ = http.NewRequest("PUT", url, bytes.NewBuffer(body))
-	req.Header.Set("Authorization", "Bearer "+githubToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err = client.Do(req)
+// BuildTermsUI menghasilkan mesej terma untuk parse mode TERMS_PARSE_MODE.
+// Teks panjang dipecah kepada beberapa mesej (had 4096 aksara Telegram).
+func BuildTermsUI() ([]string, error) {
+	data, err := currentTerms()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return RenderTerms(data, config.TermsParseMode), nil
+}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("Github API returned status %d: %s", resp.StatusCode, string(body))
-	}
+// SaveAgreementToGithub menyimpan rekod persetujuan ke backend storan aktif (Audit Log).
+// created=true hanya jika user belum mempunyai rekod persetujuan; tekanan "Setuju" berulang
+// bagi versi terma yang sama (oleh user yang masih aktif) tidak menulis semula rekod.
+func SaveAgreementToGithub(userID int64, username string) (created bool, err error) {
+	terms, hash := publishedTerms()
+	existing, getErr := store.GetAgreement(userID)
+	if getErr == nil && existing != nil && existing.TermsHash == hash && existing.InactiveSince == nil {
+		return false, nil
+	}
+	rec := AgreementRecord{
+		UserID:    userID,
+		Username:  username,
+		AgreedAt:  time.Now(),
+		Status:    "AGREED",
+		TermsHash: hash,
+	}
+	if terms != nil {
+		rec.TermsVersion = terms.Version
+	}
+	if err := store.SaveAgreement(rec); err != nil {
+		return false, err
+	}
+	return getErr == nil && existing == nil, nil
+}
 
-	return nil
+// BanUser digunakan oleh Admin untuk sekat user ke backend storan aktif.
+// byAdmin ialah nama pentadbir yang bertindak (atau sistem untuk auto-ban).
+// duration 0 bermaksud sekatan kekal.
+func BanUser(userID int64, reason string, byAdmin string, duration time.Duration) error {
+	rec := BanRecord{
+		UserID:   userID,
+		Reason:   reason,
+		BannedAt: time.Now(),
+		ByAdmin:  byAdmin,
+	}
+	if duration > 0 {
+		expires := rec.BannedAt.Add(duration)
+		rec.ExpiresAt = &expires
+	}
+	// Sekatan terdahulu (termasuk rayuan & keputusan Admin) diarkibkan dalam rekod baharu
+	if prev, err := store.GetBan(userID); err != nil {
+		log.Printf("⚠️ Gagal baca sekatan terdahulu user %d, sejarah tidak diarkibkan: %v", userID, err)
+	} else if prev != nil {
+		archived := *prev
+		archived.History = nil
+		rec.History = append(append([]BanRecord(nil), prev.History...), archived)
+	}
+	return store.SaveBan(rec)
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// IntegrityCheck ialah blok integrity_check dalam terms.json.
+// Hash dan tandatangan dikira ke atas JSON kanonik dokumen tanpa blok ini.
+type IntegrityCheck struct {
+	HashAlgorithm      string `json:"hash_algorithm"`
+	SignatureAlgorithm string `json:"signature_algorithm"`
+	Hash               string `json:"hash"`
+	Signature          string `json:"signature"`
+}
+
+// Salinan terms.json terakhir yang lulus pengesahan tandatangan
+var (
+	verifiedMu      sync.Mutex
+	lastVerified    []byte
+	lastAlertedHash string
+)
+
+// canonicalTermsJSON menghasilkan bentuk kanonik terms.json: tanpa integrity_check,
+// kunci objek disusun mengikut abjad dan tiada ruang putih
+func canonicalTermsJSON(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("gagal parse JSON: %v", err)
+	}
+	delete(doc, "integrity_check")
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// parseTermsKey menerima kunci ed25519 dalam base64 atau hex
+func parseTermsKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	if b, err := hex.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return nil, errors.New("kunci mesti dalam format base64 atau hex")
+}
+
+// verifyTermsSignature menyemak blok integrity_check terms.json dengan kunci awam
+func verifyTermsSignature(body []byte, publicKey ed25519.PublicKey) error {
+	var doc struct {
+		Integrity *IntegrityCheck `json:"integrity_check"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("gagal parse JSON: %v", err)
+	}
+	if doc.Integrity == nil || doc.Integrity.Signature == "" {
+		return errors.New("blok integrity_check tiada tandatangan")
+	}
+	if !strings.EqualFold(doc.Integrity.SignatureAlgorithm, "ed25519") {
+		return fmt.Errorf("algoritma tandatangan tidak disokong: %q", doc.Integrity.SignatureAlgorithm)
+	}
+
+	canonical, err := canonicalTermsJSON(body)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(canonical)
+	if !strings.EqualFold(doc.Integrity.Hash, hex.EncodeToString(sum[:])) {
+		return errors.New("hash SHA-256 tidak sepadan dengan kandungan")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(doc.Integrity.Signature)
+	if err != nil {
+		return fmt.Errorf("tandatangan bukan base64: %v", err)
+	}
+	if !ed25519.Verify(publicKey, canonical, sig) {
+		return errors.New("tandatangan ed25519 tidak sah")
+	}
+	return nil
+}
+
+// verifiedTermsPath ialah lokasi salinan terakhir yang sah di cakera
+func verifiedTermsPath() string {
+	return filepath.Join(config.StateDir, "terms_verified.json")
+}
+
+// verifiedTermsBody memulangkan body jika tandatangannya sah (atau pengesahan dimatikan).
+// Jika tidak sah, Admin dimaklumkan dan salinan sah terakhir dipulangkan sebagai ganti.
+func verifiedTermsBody(body []byte) ([]byte, error) {
+	if len(config.TermsPublicKey) == 0 {
+		return body, nil
+	}
+
+	verifiedMu.Lock()
+	defer verifiedMu.Unlock()
+
+	err := verifyTermsSignature(body, config.TermsPublicKey)
+	if err == nil {
+		if !bytes.Equal(body, lastVerified) {
+			lastVerified = body
+			if werr := os.WriteFile(verifiedTermsPath(), body, 0o644); werr != nil {
+				log.Printf("⚠️ Gagal simpan salinan terma yang sah: %v", werr)
+			}
+		}
+		return body, nil
+	}
+
+	log.Printf("🚨 terms.json gagal pengesahan tandatangan: %v", err)
+	// Maklumkan Admin sekali sahaja bagi setiap kandungan yang diubah
+	if hash := termsContentHash(body); hash != lastAlertedHash {
+		lastAlertedHash = hash
+		alertAdmin(fmt.Sprintf(
+			"🚨 *AMARAN INTEGRITI TERMA*\n\n"+
+				"terms.json gagal pengesahan tandatangan dan *TIDAK* dipaparkan kepada user.\n\n"+
+				"Sebab: %v\n"+
+				"SHA-256 kandungan: `%s`\n\n"+
+				"_Bot menggunakan salinan sah yang terakhir._", err, hash))
+	}
+
+	if lastVerified == nil {
+		if data, rerr := os.ReadFile(verifiedTermsPath()); rerr == nil && verifyTermsSignature(data, config.TermsPublicKey) == nil {
+			lastVerified = data
+		}
+	}
+	if lastVerified == nil {
+		return nil, fmt.Errorf("terma gagal disahkan dan tiada salinan sah: %v", err)
+	}
+	return lastVerified, nil
+}
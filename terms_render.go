@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// telegramMessageLimit ialah had panjang teks satu mesej Telegram
+const telegramMessageLimit = 4096
+
+// Teks dalam terms.json ditulis dalam Markdown legacy Telegram (*tebal*, _condong_).
+// legacyInline mengenal pasti penanda tersebut supaya boleh ditukar ke mod lain.
+var legacyInline = regexp.MustCompile(`\*([^*\n]+)\*|_([^_\n]+)_`)
+
+// markdownV2Special ialah aksara yang wajib di-escape dalam MarkdownV2
+var markdownV2Special = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// termsMarkup menghasilkan teks yang betul untuk satu parse mode Telegram
+type termsMarkup struct {
+	mode string
+}
+
+// text menukar teks Markdown legacy dari terms.json kepada format mod semasa
+func (m termsMarkup) text(s string) string {
+	switch m.mode {
+	case tgbotapi.ModeMarkdown:
+		return s
+	case tgbotapi.ModeMarkdownV2:
+		return m.convert(s, markdownV2Special.Replace, "*", "*", "_", "_")
+	case tgbotapi.ModeHTML:
+		return m.convert(s, html.EscapeString, "<b>", "</b>", "<i>", "</i>")
+	default:
+		return m.convert(s, func(t string) string { return t }, "", "", "", "")
+	}
+}
+
+func (m termsMarkup) convert(s string, escape func(string) string, bOpen, bClose, iOpen, iClose string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range legacyInline.FindAllStringSubmatchIndex(s, -1) {
+		sb.WriteString(escape(s[last:loc[0]]))
+		if loc[2] >= 0 {
+			sb.WriteString(bOpen + escape(s[loc[2]:loc[3]]) + bClose)
+		} else {
+			sb.WriteString(iOpen + escape(s[loc[4]:loc[5]]) + iClose)
+		}
+		last = loc[1]
+	}
+	sb.WriteString(escape(s[last:]))
+	return sb.String()
+}
+
+// bold menebalkan teks (penanda * sedia ada di hujung dibuang dahulu)
+func (m termsMarkup) bold(s string) string {
+	s = strings.Trim(strings.TrimSpace(s), "*")
+	switch m.mode {
+	case tgbotapi.ModeMarkdown:
+		return "*" + s + "*"
+	case tgbotapi.ModeMarkdownV2:
+		return "*" + markdownV2Special.Replace(s) + "*"
+	case tgbotapi.ModeHTML:
+		return "<b>" + html.EscapeString(s) + "</b>"
+	}
+	return s
+}
+
+// italic mencondongkan teks (penanda _ atau * sedia ada di hujung dibuang dahulu)
+func (m termsMarkup) italic(s string) string {
+	s = strings.Trim(strings.TrimSpace(s), "*_")
+	switch m.mode {
+	case tgbotapi.ModeMarkdown:
+		return "_" + s + "_"
+	case tgbotapi.ModeMarkdownV2:
+		return "_" + markdownV2Special.Replace(s) + "_"
+	case tgbotapi.ModeHTML:
+		return "<i>" + html.EscapeString(s) + "</i>"
+	}
+	return s
+}
+
+// RenderTerms menghasilkan dokumen terma penuh (tajuk, versi, intro, seksyen, footer,
+// hak cipta) untuk parse mode yang dipilih, dipecah kepada beberapa mesej jika perlu
+func RenderTerms(data *TermsData, parseMode string) []string {
+	m := termsMarkup{mode: parseMode}
+	tc := data.TermsAndConditions
+	var blocks []string
+
+	// Header
+	header := m.bold(tc.Title)
+	var meta []string
+	if data.Version != "" {
+		meta = append(meta, "Versi "+data.Version)
+	}
+	if data.LastUpdated != "" {
+		meta = append(meta, "Dikemaskini "+data.LastUpdated)
+	}
+	if len(meta) > 0 {
+		header += "\n" + m.italic(strings.Join(meta, " • "))
+	}
+	blocks = append(blocks, header)
+
+	if tc.Intro != "" {
+		blocks = append(blocks, m.text(tc.Intro))
+	} else {
+		blocks = append(blocks, m.text("Sila baca dan patuhi terma dan syarat berikut:"))
+	}
+
+	// Sections
+	for _, sec := range tc.Sections {
+		var sb strings.Builder
+		sb.WriteString(m.text(fmt.Sprintf("%d. ", sec.ID)) + m.bold(sec.Heading))
+		for _, line := range sec.Content {
+			sb.WriteString("\n" + m.text("• ") + m.text(line))
+		}
+		blocks = append(blocks, sb.String())
+	}
+
+	// Footer
+	var footer []string
+	footer = append(footer, "───────────────────────")
+	if tc.Footer != "" {
+		footer = append(footer, m.text(tc.Footer))
+	}
+	if tc.Copyright != "" {
+		footer = append(footer, m.text(tc.Copyright))
+	}
+	footer = append(footer, "", m.italic("Untuk teruskan sesi operasi bot sila pilih:"))
+	blocks = append(blocks, strings.Join(footer, "\n"))
+
+	return splitMessages(blocks, telegramMessageLimit)
+}
+
+// textLength mengira panjang mengikut unit UTF-16 (cara Telegram mengira had mesej)
+func textLength(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// splitMessages menggabungkan blok menjadi mesej yang tidak melebihi had. Pemecahan
+// berlaku di antara blok (kemudian baris) supaya penanda format tidak terputus.
+func splitMessages(blocks []string, limit int) []string {
+	var parts []string
+	var cur strings.Builder
+
+	flush := func() {
+		if cur.Len() > 0 {
+			parts = append(parts, cur.String())
+			cur.Reset()
+		}
+	}
+	add := func(piece, sep string) {
+		if cur.Len() > 0 && textLength(cur.String())+textLength(sep)+textLength(piece) > limit {
+			flush()
+		}
+		if cur.Len() > 0 {
+			cur.WriteString(sep)
+		}
+		cur.WriteString(piece)
+	}
+
+	for _, block := range blocks {
+		if textLength(block) <= limit {
+			add(block, "\n\n")
+			continue
+		}
+		// Blok terlalu panjang: pecah mengikut baris, dan baris mengikut rune jika perlu
+		for i, line := range strings.Split(block, "\n") {
+			sep := "\n"
+			if i == 0 {
+				sep = "\n\n"
+			}
+			for textLength(line) > limit {
+				r := []rune(line)
+				n := limit
+				for textLength(string(r[:n])) > limit {
+					n--
+				}
+				flush()
+				parts = append(parts, string(r[:n]))
+				line = string(r[n:])
+			}
+			add(line, sep)
+		}
+	}
+	flush()
+	return parts
+}
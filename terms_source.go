@@ -0,0 +1,124 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// embeddedTerms ialah salinan terms.json yang dibina bersama binari (sandaran terakhir)
+//
+//go:embed terms.json
+var embeddedTerms []byte
+
+// termsClient mempunyai had masa supaya /start tidak tergantung jika GitHub lambat
+var termsClient = &http.Client{Timeout: 10 * time.Second}
+
+// Pengesah untuk permintaan bersyarat ke termsURL
+var termsRemote struct {
+	sync.Mutex
+	etag         string
+	lastModified string
+}
+
+// publishTermsBody parse terms.json dan menjadikannya terma terbitan semasa
+func publishTermsBody(body []byte) (*TermsData, error) {
+	var data TermsData
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("gagal parse JSON: %v", err)
+	}
+	hash := termsContentHash(body)
+	if prev, prevHash := publishedTerms(); prev != nil && prevHash != hash {
+		logAudit(AuditRecord{
+			Actor:  auditSystemActor,
+			Action: auditContentReload,
+			Source: auditSourceAuto,
+			Reason: "Kandungan terms.json berubah",
+			Detail: fmt.Sprintf("terma v%s → v%s (hash %.12s)", prev.Version, data.Version, hash),
+		})
+	}
+	setPublishedTerms(&data, hash)
+	return &data, nil
+}
+
+// loadTerms memuatkan terma semasa startup: URL jauh dahulu, kemudian salinan sah
+// terakhir, fail tempatan TERMS_FILE, dan akhirnya salinan terbenam dalam binari
+func loadTerms() {
+	data, err := fetchTerms()
+	if err == nil {
+		log.Printf("📜 Terma v%s dimuatkan dari %s", data.Version, termsURL)
+		return
+	}
+	log.Printf("⚠️ Gagal memuatkan terma dari URL: %v", err)
+
+	type candidate struct {
+		name string
+		body []byte
+		// lastResort: digunakan walaupun gagal pengesahan (dengan amaran kepada Admin)
+		// supaya bot tidak kehilangan terma sepenuhnya
+		lastResort bool
+	}
+	var candidates []candidate
+	if body, err := os.ReadFile(verifiedTermsPath()); err == nil {
+		candidates = append(candidates, candidate{"salinan sah terakhir", body, false})
+	}
+	if body, err := os.ReadFile(config.TermsFile); err == nil {
+		candidates = append(candidates, candidate{config.TermsFile, body, false})
+	}
+	candidates = append(candidates, candidate{"salinan terbenam", embeddedTerms, true})
+
+	for _, c := range candidates {
+		if len(config.TermsPublicKey) > 0 {
+			if err := verifyTermsSignature(c.body, config.TermsPublicKey); err != nil {
+				log.Printf("⚠️ Terma dari %s gagal pengesahan: %v", c.name, err)
+				if !c.lastResort {
+					continue
+				}
+				alertAdmin(fmt.Sprintf(
+					"🚨 *AMARAN INTEGRITI TERMA*\n\n"+
+						"Tiada salinan terms.json yang sah. Bot menggunakan salinan terbenam yang *TIDAK DISAHKAN*.\n\n"+
+						"Sebab: %v\n\n"+
+						"_Tandatangan semula terms.json dengan `sign-terms` (lihat README)._", err))
+			}
+		}
+		if data, err := publishTermsBody(c.body); err == nil {
+			log.Printf("📜 Terma v%s dimuatkan dari %s", data.Version, c.name)
+			return
+		}
+	}
+	log.Printf("❌ Tiada sumber terma yang boleh digunakan")
+}
+
+// startTermsRefresh menyegarkan terma secara berkala di latar belakang.
+// Jika URL gagal, terma dalam memori terus digunakan. interval <= 0 mematikannya.
+func startTermsRefresh(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := fetchTerms(); err != nil {
+				log.Printf("⚠️ Gagal segarkan terma: %v", err)
+			}
+		}
+	}()
+}
+
+// currentTerms memulangkan terma dalam memori (dimuatkan semula jika belum ada)
+func currentTerms() (*TermsData, error) {
+	if data, _ := publishedTerms(); data != nil {
+		return data, nil
+	}
+	loadTerms()
+	if data, _ := publishedTerms(); data != nil {
+		return data, nil
+	}
+	return nil, fmt.Errorf("terma tidak tersedia")
+}
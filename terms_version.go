@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// legacyTermsVersion ialah versi yang dianggap dipersetujui oleh rekod lama tanpa terms_version
+const legacyTermsVersion = "1.0.0"
+
+// TermsChange ialah ringkasan perubahan bagi satu versi terma
+type TermsChange struct {
+	Version string   `json:"version"`
+	Summary []string `json:"summary"`
+}
+
+// Terma terbitan semasa (dikemaskini setiap kali terms.json berjaya dimuatkan)
+var (
+	publishedMu   sync.RWMutex
+	published     *TermsData
+	publishedHash string
+)
+
+func setPublishedTerms(data *TermsData, hash string) {
+	publishedMu.Lock()
+	defer publishedMu.Unlock()
+	published, publishedHash = data, hash
+}
+
+// publishedTerms memulangkan terma semasa dan hash kandungannya (nil jika belum dimuatkan)
+func publishedTerms() (*TermsData, string) {
+	publishedMu.RLock()
+	defer publishedMu.RUnlock()
+	return published, publishedHash
+}
+
+// termsContentHash mengira hash SHA-256 (hex) kandungan terms.json
+func termsContentHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// compareVersions membandingkan versi "x.y.z" secara numerik: -1, 0 atau 1
+func compareVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// termsOutdated memulangkan true jika versi terbitan sudah melepasi versi yang dipersetujui.
+// Jika terma belum dapat dimuatkan, persetujuan sedia ada dianggap sah.
+func termsOutdated(acceptedVersion string) bool {
+	terms, _ := publishedTerms()
+	if terms == nil || terms.Version == "" {
+		return false
+	}
+	if acceptedVersion == "" {
+		acceptedVersion = legacyTermsVersion
+	}
+	return compareVersions(terms.Version, acceptedVersion) > 0
+}
+
+// ReconsentVersion memulangkan versi terma yang pernah dipersetujui user jika ia sudah lapuk,
+// atau "" jika user tiada rekod / persetujuannya masih sah
+func ReconsentVersion(userID int64) string {
+	rec, err := store.GetAgreement(userID)
+	if err != nil || rec == nil {
+		return ""
+	}
+	version := rec.TermsVersion
+	if version == "" {
+		version = legacyTermsVersion
+	}
+	if !termsOutdated(version) {
+		return ""
+	}
+	return version
+}
+
+// BuildTermsChangesUI menyenaraikan perubahan sejak versi yang dipersetujui user
+func BuildTermsChangesUI(acceptedVersion string) string {
+	terms, _ := publishedTerms()
+	if terms == nil {
+		return ""
+	}
+	m := termsMarkup{mode: config.TermsParseMode}
+
+	var sb strings.Builder
+	sb.WriteString(m.bold("📢 TERMA TELAH DIKEMASKINI"))
+	sb.WriteString(m.text(fmt.Sprintf(" (v%s → v%s)", acceptedVersion, terms.Version)))
+	sb.WriteString("\n\n")
+
+	var lines []string
+	for _, change := range terms.Changes {
+		if compareVersions(change.Version, acceptedVersion) <= 0 {
+			continue
+		}
+		for _, s := range change.Summary {
+			lines = append(lines, m.text("• ")+m.text(s))
+		}
+	}
+	if len(lines) > 0 {
+		sb.WriteString(m.text("Ringkasan perubahan:") + "\n")
+		sb.WriteString(strings.Join(lines, "\n"))
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString(m.italic("Sila baca terma terkini di bawah dan bersetuju semula untuk meneruskan."))
+	return sb.String()
+}
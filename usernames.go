@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// usernameIndex memetakan username (huruf kecil) kepada ID user. Diisi dari setiap
+// update yang dilihat bot dan dari rekod persetujuan dalam storan.
+var usernameIndex = struct {
+	sync.Mutex
+	byName map[string]map[int64]time.Time // username -> ID -> kali terakhir dilihat
+	byID   map[int64]string
+}{byName: make(map[string]map[int64]time.Time), byID: make(map[int64]string)}
+
+// observeUsername merekod username semasa seorang user
+func observeUsername(userID int64, username string, seen time.Time) {
+	if userID == 0 || username == "" {
+		return
+	}
+	name := strings.ToLower(username)
+
+	usernameIndex.Lock()
+	defer usernameIndex.Unlock()
+	if old, ok := usernameIndex.byID[userID]; ok && old != name {
+		// User menukar username: buang pemetaan lama
+		delete(usernameIndex.byName[old], userID)
+		if len(usernameIndex.byName[old]) == 0 {
+			delete(usernameIndex.byName, old)
+		}
+	}
+	ids := usernameIndex.byName[name]
+	if ids == nil {
+		ids = make(map[int64]time.Time)
+		usernameIndex.byName[name] = ids
+	}
+	if seen.After(ids[userID]) {
+		ids[userID] = seen
+	}
+	usernameIndex.byID[userID] = name
+}
+
+// observeUpdate mengisi indeks dari pengirim update dan mesej yang dikemukakan (forward)
+func observeUpdate(update tgbotapi.Update) {
+	now := time.Now()
+	if cb := update.CallbackQuery; cb != nil && cb.From != nil {
+		observeUsername(cb.From.ID, cb.From.UserName, now)
+	}
+	if msg := update.Message; msg != nil {
+		if msg.From != nil {
+			observeUsername(msg.From.ID, msg.From.UserName, now)
+		}
+		if msg.ForwardFrom != nil {
+			observeUsername(msg.ForwardFrom.ID, msg.ForwardFrom.UserName, now)
+		}
+	}
+}
+
+// loadUsernameIndex mengisi indeks dari rekod persetujuan yang disimpan
+func loadUsernameIndex() {
+	recs, err := store.ListAgreements()
+	if err != nil {
+		log.Printf("⚠️ Gagal isi indeks username dari storan: %v", err)
+		return
+	}
+	for _, r := range recs {
+		observeUsername(r.UserID, r.Username, r.AgreedAt)
+	}
+	log.Printf("📇 Indeks username: %d rekod persetujuan dimuatkan", len(recs))
+}
+
+// lookupUsername mencari ID bagi username. Username yang dikaitkan dengan lebih
+// daripada satu ID (contoh: username bertukar tangan) dilaporkan sebagai kabur.
+func lookupUsername(username string) (int64, error) {
+	name := strings.ToLower(strings.TrimPrefix(username, "@"))
+
+	usernameIndex.Lock()
+	ids := usernameIndex.byName[name]
+	var matches []int64
+	for id := range ids {
+		matches = append(matches, id)
+	}
+	usernameIndex.Unlock()
+
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("username @%s tidak dikenali (user belum pernah berinteraksi dengan bot). Sila guna ID atau balas mesej yang dikemukakan (forward) daripada user tersebut.", name)
+	case 1:
+		return matches[0], nil
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i] < matches[j] })
+	idStrs := make([]string, len(matches))
+	for i, id := range matches {
+		idStrs[i] = strconv.FormatInt(id, 10)
+	}
+	return 0, fmt.Errorf("username @%s kabur: dikaitkan dengan beberapa ID (%s). Sila guna ID.", name, strings.Join(idStrs, ", "))
+}
+
+// resolveUserRef menukar "<id>" atau "@username" kepada ID user
+func resolveUserRef(ref string) (int64, error) {
+	if strings.HasPrefix(ref, "@") {
+		return lookupUsername(ref)
+	}
+	id, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ID tidak sah: %s. Sila masukkan nombor ID atau @username.", ref)
+	}
+	return id, nil
+}
+
+// errNoTarget: arahan tiada sasaran (tiada argumen dan tidak membalas mesej)
+var errNoTarget = errors.New("tiada sasaran")
+
+// isUserRef menyemak sama ada argumen ditulis sebagai sasaran ("<id>" atau "@username"),
+// bukan tempoh atau sebab
+func isUserRef(arg string) bool {
+	if strings.HasPrefix(arg, "@") {
+		return len(arg) > 1
+	}
+	_, err := strconv.ParseInt(arg, 10, 64)
+	return err == nil
+}
+
+// commandTarget menentukan user sasaran arahan Admin: dari argumen pertama (ID / @username)
+// atau dari mesej yang dibalas (mesej dikemukakan daripada user). Argumen yang ditulis
+// secara jelas sentiasa menang ke atas mesej yang dibalas. Memulangkan argumen selebihnya.
+func commandTarget(ctx *CommandContext) (int64, []string, error) {
+	if len(ctx.Args) > 0 && isUserRef(ctx.Args[0]) {
+		id, err := resolveUserRef(ctx.Args[0])
+		return id, ctx.Args[1:], err
+	}
+	if reply := ctx.Message.ReplyToMessage; reply != nil {
+		switch {
+		case reply.ForwardFrom != nil:
+			return reply.ForwardFrom.ID, ctx.Args, nil
+		case reply.ForwardSenderName != "":
+			return 0, nil, fmt.Errorf("%s menyembunyikan akaun dalam mesej yang dikemukakan. Sila guna ID atau @username.", reply.ForwardSenderName)
+		case reply.From != nil && !reply.From.IsBot && reply.From.ID != ctx.UserID:
+			// Dalam kumpulan: balas terus kepada mesej user
+			return reply.From.ID, ctx.Args, nil
+		}
+	}
+	if len(ctx.Args) == 0 {
+		return 0, nil, errNoTarget
+	}
+	id, err := resolveUserRef(ctx.Args[0])
+	return id, ctx.Args[1:], err
+}
+
+// replyTargetError memaparkan ralat commandTarget kepada Admin
+func replyTargetError(ctx *CommandContext, err error) {
+	if errors.Is(err, errNoTarget) {
+		ctx.Reply(fmt.Sprintf("⚠️ Format salah: %s\n(atau balas mesej yang dikemukakan daripada user)", ctx.Cmd.Usage()))
+		return
+	}
+	ctx.Reply("❌ " + err.Error())
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Data callback butang pintas /whois: "<tindakan>:<user_id>"
+const (
+	cbWhoisBan        = "whois_ban"
+	cbWhoisBanConfirm = "whois_banok"
+	cbWhoisCancel     = "whois_cancel"
+	cbWhoisUnban      = "whois_unban"
+)
+
+// guideLabels ialah nama paparan kaunter panduan dalam /whois
+var guideLabels = map[string]string{
+	statGuideClaim:   "Worldcoin",
+	statGuideWallet:  "HATA",
+	statGuideCashout: "Cashout",
+	statGuideWebsite: "Website",
+	statInfographic:  "Infografik",
+}
+
+const whoisTimeFormat = "2006-01-02 15:04:05"
+
+// whoisMaxStrikes ialah bilangan kesalahan spam terkini yang dipaparkan
+const whoisMaxStrikes = 10
+
+// strikeLines menyenaraikan kesalahan spam terkini (terbaru dahulu)
+func strikeLines(rec StrikeRecord, limit int) string {
+	var sb strings.Builder
+	for i := len(rec.Events) - 1; i >= 0 && i >= len(rec.Events)-limit; i-- {
+		ev := rec.Events[i]
+		sb.WriteString(fmt.Sprintf("   %s — %s, chat %d → %s\n",
+			ev.At.Format(whoisTimeFormat), ev.Action, ev.ChatID, ev.Penalty))
+	}
+	return sb.String()
+}
+
+// BuildWhoisReport mengumpul semua maklumat tentang user (teks biasa).
+// Memulangkan juga sama ada user sedang disekat (untuk butang pintas).
+func BuildWhoisReport(userID int64) (string, bool) {
+	now := time.Now()
+	var sb strings.Builder
+
+	activity, seen := activityOf(userID)
+	agreement, agreementErr := store.GetAgreement(userID)
+	ban, banErr := store.GetBan(userID)
+
+	username := activity.Username
+	if username == "" && agreement != nil {
+		username = agreement.Username
+	}
+	if username != "" {
+		sb.WriteString(fmt.Sprintf("🔎 WHOIS: %d (@%s)\n", userID, username))
+	} else {
+		sb.WriteString(fmt.Sprintf("🔎 WHOIS: %d\n", userID))
+	}
+	sb.WriteString(fmt.Sprintf("Peranan: %s\n", RoleOf(userID)))
+
+	sb.WriteString("\n📝 Persetujuan\n")
+	switch {
+	case agreementErr != nil:
+		sb.WriteString(fmt.Sprintf("Ralat storan: %v\n", agreementErr))
+	case agreement == nil:
+		sb.WriteString("Tiada rekod persetujuan\n")
+	default:
+		sb.WriteString(fmt.Sprintf("Tarikh: %s\n", agreement.AgreedAt.Format(whoisTimeFormat)))
+		version := agreement.TermsVersion
+		if version == "" {
+			version = legacyTermsVersion
+		}
+		if termsOutdated(agreement.TermsVersion) {
+			version += " (perlu bersetuju semula)"
+		}
+		sb.WriteString(fmt.Sprintf("Versi terma: %s\n", version))
+		if agreement.InactiveSince != nil {
+			sb.WriteString(fmt.Sprintf("Tidak aktif sejak: %s\n", agreement.InactiveSince.Format(whoisTimeFormat)))
+		}
+	}
+
+	banned := false
+	sb.WriteString("\n🚫 Sekatan\n")
+	switch {
+	case banErr != nil:
+		sb.WriteString(fmt.Sprintf("Ralat storan: %v\n", banErr))
+	case ban == nil:
+		sb.WriteString("Tiada rekod sekatan\n")
+	default:
+		banned = ban.Active(now)
+		status := "AKTIF"
+		switch {
+		case ban.LiftedAt != nil && (ban.ExpiresAt == nil || ban.LiftedAt.Before(*ban.ExpiresAt)):
+			status = "ditarik balik " + ban.LiftedAt.Format(whoisTimeFormat)
+		case ban.Expired(now):
+			status = "tamat tempoh"
+		}
+		sb.WriteString(fmt.Sprintf("Status: %s\n", status))
+		sb.WriteString(fmt.Sprintf("Sebab: %s\n", ban.Reason))
+		sb.WriteString(fmt.Sprintf("Oleh: %s\n", ban.ByAdmin))
+		sb.WriteString(fmt.Sprintf("Pada: %s\n", ban.BannedAt.Format(whoisTimeFormat)))
+		if ban.ExpiresAt != nil {
+			sb.WriteString(fmt.Sprintf("Tamat: %s\n", ban.ExpiresAt.Format(whoisTimeFormat)))
+		} else {
+			sb.WriteString("Tamat: kekal\n")
+		}
+		if ban.Appeal != nil {
+			sb.WriteString(fmt.Sprintf("Rayuan: %s (%d mesej)\n", ban.Appeal.Status, len(ban.Appeal.Messages)))
+		}
+		if n := len(ban.History); n > 0 {
+			sb.WriteString(fmt.Sprintf("Sekatan terdahulu: %d\n", n))
+		}
+	}
+
+	sb.WriteString("\n🛡️ Anti-spam\n")
+	limiter, _ := spamLimiterFor(RoleOf(userID))
+	if tokens, ok := limiter.Tokens(userID); ok {
+		sb.WriteString(fmt.Sprintf("Baki token: %.1f/%g\n", tokens, limiter.Limit().Burst))
+	} else {
+		sb.WriteString("Tiada rekod\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("\n⚠️ Kesalahan spam (simpanan %s)\n", formatPenaltyDuration(strikeRetention())))
+	if strikes, ok := StrikeHistory(userID); ok && len(strikes.Events) > 0 {
+		sb.WriteString(fmt.Sprintf("Dikira untuk hukuman: %d (dalam %s)\n",
+			strikes.Countable(now, config.SpamLookback), formatPenaltyDuration(config.SpamLookback)))
+		if strikes.ClearedAt != nil {
+			sb.WriteString(fmt.Sprintf("Dimaafkan: %s\n", strikes.ClearedAt.Format(whoisTimeFormat)))
+		}
+		sb.WriteString(strikeLines(strikes, whoisMaxStrikes))
+	} else {
+		sb.WriteString("Tiada rekod\n")
+	}
+
+	sb.WriteString("\n🕒 Aktiviti (sejak bot dimulakan)\n")
+	if !seen {
+		sb.WriteString("Tiada aktiviti\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("Terakhir: %s — %s\n", activity.LastSeen.Format(whoisTimeFormat), activity.LastAction))
+		if len(activity.Guides) == 0 {
+			sb.WriteString("Panduan: tiada\n")
+		} else {
+			sb.WriteString("Panduan:\n")
+			for i := len(activity.Guides) - 1; i >= 0; i-- {
+				g := activity.Guides[i]
+				sb.WriteString(fmt.Sprintf("   %s (%s)\n", guideLabels[g.Guide], g.At.Format(whoisTimeFormat)))
+			}
+		}
+	}
+	return sb.String(), banned
+}
+
+// whoisMarkup membina butang pintas mengikut kebenaran Admin
+func whoisMarkup(role Role, userID int64, banned bool, withMessage bool) *tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	if !banned && role.Can(PermBan) {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("🚫 Sekat", fmt.Sprintf("%s:%d", cbWhoisBan, userID)))
+	}
+	if banned && role.Can(PermUnban) {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("✅ Buang Sekatan", fmt.Sprintf("%s:%d", cbWhoisUnban, userID)))
+	}
+	if withMessage {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonURL("💬 Mesej", fmt.Sprintf("tg://user?id=%d", userID)))
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(row)
+	return &markup
+}
+
+func handleWhois(ctx *CommandContext) {
+	targetID, _, err := commandTarget(ctx)
+	if err != nil {
+		replyTargetError(ctx, err)
+		return
+	}
+	report, banned := BuildWhoisReport(targetID)
+
+	msg := tgbotapi.NewMessage(ctx.ChatID, report)
+	if markup := whoisMarkup(ctx.Role, targetID, banned, true); markup != nil {
+		msg.ReplyMarkup = markup
+	}
+	sent, err := ctx.Bot.Send(msg)
+	if err != nil {
+		// Butang mesej ditolak jika tetapan privasi user menghalang pautan profil
+		msg.ReplyMarkup = nil
+		if markup := whoisMarkup(ctx.Role, targetID, banned, false); markup != nil {
+			msg.ReplyMarkup = markup
+		}
+		if sent, err = ctx.Bot.Send(msg); err != nil {
+			log.Printf("Gagal hantar /whois: %v", err)
+			return
+		}
+	}
+	ctx.Track(sent.MessageID)
+}
+
+// isWhoisCallback menyemak sama ada data callback ialah butang pintas /whois
+func isWhoisCallback(data string) bool {
+	return strings.HasPrefix(data, "whois_")
+}
+
+// handleWhoisCallback memproses butang Sekat (dengan pengesahan) / Buang Sekatan dari /whois
+func handleWhoisCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	adminID := callback.From.ID
+	action, idStr, _ := strings.Cut(callback.Data, ":")
+	targetID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || callback.Message == nil {
+		bot.Request(tgbotapi.NewCallback(callback.ID, "Data tidak sah"))
+		return
+	}
+
+	var result string
+	switch {
+	case action == cbWhoisBan && HasPermission(adminID, PermBan):
+		// Sekatan kekal: minta pengesahan dahulu
+		if err := checkBanTarget(adminID, targetID); err != nil {
+			bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "❌ "+err.Error()))
+			return
+		}
+		confirm := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⚠️ Sahkan Sekatan Kekal", fmt.Sprintf("%s:%d", cbWhoisBanConfirm, targetID)),
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Batal", fmt.Sprintf("%s:%d", cbWhoisCancel, targetID)),
+		))
+		bot.Request(tgbotapi.NewCallback(callback.ID, fmt.Sprintf("Sahkan sekatan kekal ke atas user %d?", targetID)))
+		bot.Send(tgbotapi.NewEditMessageReplyMarkup(callback.Message.Chat.ID, callback.Message.MessageID, confirm))
+		return
+	case action == cbWhoisCancel && HasPermission(adminID, PermBan):
+		bot.Request(tgbotapi.NewCallback(callback.ID, "Dibatalkan"))
+		refreshWhois(bot, callback.Message, adminID, targetID)
+		return
+	case action == cbWhoisBanConfirm && HasPermission(adminID, PermBan):
+		if err := checkBanTarget(adminID, targetID); err != nil {
+			result = "❌ " + err.Error()
+		} else if err := ManualBan(bot, adminID, targetID, "Sekatan Manual oleh Admin", StaffName(adminID, callback.From.UserName), 0); err != nil {
+			result = fmt.Sprintf("❌ Gagal menyekat user: %v", err)
+		} else {
+			result = fmt.Sprintf("✅ User %d telah berjaya disekat dan notis telah dihantar.", targetID)
+		}
+	case action == cbWhoisUnban && HasPermission(adminID, PermUnban):
+		notified, err := UnbanUser(bot, adminID, targetID)
+		result = unbanResultText(targetID, notified, err)
+	default:
+		bot.Request(tgbotapi.NewCallback(callback.ID, "Tiada kebenaran"))
+		return
+	}
+	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, result))
+	refreshWhois(bot, callback.Message, adminID, targetID)
+}
+
+// refreshWhois menyegarkan laporan supaya butang sepadan dengan status terkini
+func refreshWhois(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, adminID, targetID int64) {
+	report, banned := BuildWhoisReport(targetID)
+	edit := tgbotapi.NewEditMessageText(msg.Chat.ID, msg.MessageID, report)
+	edit.ReplyMarkup = whoisMarkup(RoleOf(adminID), targetID, banned, true)
+	if _, err := bot.Send(edit); err != nil {
+		edit.ReplyMarkup = whoisMarkup(RoleOf(adminID), targetID, banned, false)
+		bot.Send(edit)
+	}
+}